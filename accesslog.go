@@ -0,0 +1,291 @@
+//
+// accesslog.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// 访问日志格式
+const (
+	AccessLogJSON     = "json"
+	AccessLogCommon   = "common"   // Common Log Format
+	AccessLogCombined = "combined" // Combined Log Format, 在 Common 基础上增加 Referer 和 User-Agent
+)
+
+// 访问日志 JSON 字段
+const (
+	FieldTime      = "time"
+	FieldMethod    = "method"
+	FieldPath      = "path"
+	FieldRoute     = "route"
+	FieldStatus    = "status"
+	FieldSize      = "size"
+	FieldLatency   = "latency"
+	FieldIP        = "ip"
+	FieldUserAgent = "user_agent"
+	FieldUser      = "user"
+	FieldRequestID = "request_id"
+	FieldReferer   = "referer"
+	FieldProto     = "proto"
+	FieldHost      = "host"
+)
+
+// DefaultAccessLogFields JSON 格式默认输出的字段
+var DefaultAccessLogFields = []string{
+	FieldTime, FieldMethod, FieldPath, FieldRoute, FieldStatus, FieldSize,
+	FieldLatency, FieldIP, FieldUserAgent, FieldUser, FieldRequestID,
+}
+
+// AccessLog 访问日志, 通过 WithAccessLog 设置后替代 DisableReqLog 控制的调试日志
+//
+//	vigo.WithAccessLog(&vigo.AccessLog{
+//	    Format:     vigo.AccessLogJSON,
+//	    Output:     logFile,
+//	    SkipPaths:  []string{"/healthz", "/readyz", "/livez"},
+//	    SampleRate: 0.1,       // 只记录 10% 的请求, 5xx 总是记录
+//	    UserID:     auth.UserID, // 如 contrib/auth 的 Auth.UserID
+//	})
+type AccessLog struct {
+	// Format json (默认), common 或 combined
+	Format string
+	// Fields JSON 格式输出的字段, 默认 DefaultAccessLogFields
+	Fields []string
+	// Output 输出位置, 默认 os.Stdout
+	Output io.Writer
+	// SampleRate 采样比例 (0, 1), 0 或 1 表示全部记录; 状态码 >= 500 的请求总是记录
+	SampleRate float64
+	// SkipPaths 不记录的路径, 如健康检查
+	SkipPaths []string
+	// Skip 返回 true 时不记录
+	Skip func(r *http.Request) bool
+	// UserID 返回当前用户, 在处理函数执行后调用
+	UserID func(x *X) string
+
+	once   sync.Once
+	mu     sync.Mutex
+	fields map[string]bool
+	json   zerolog.Logger
+}
+
+func (l *AccessLog) init() {
+	l.once.Do(func() {
+		if l.Output == nil {
+			l.Output = os.Stdout
+		}
+		fields := l.Fields
+		if len(fields) == 0 {
+			fields = DefaultAccessLogFields
+		}
+		l.fields = make(map[string]bool, len(fields))
+		for _, f := range fields {
+			l.fields[f] = true
+		}
+		l.json = zerolog.New(&lockedWriter{mu: &l.mu, w: l.Output})
+	})
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+func (l *AccessLog) skip(r *http.Request) bool {
+	if slices.Contains(l.SkipPaths, r.URL.Path) {
+		return true
+	}
+	return l.Skip != nil && l.Skip(r)
+}
+
+// serve 记录 next 处理的请求
+func (l *AccessLog) serve(w http.ResponseWriter, r *http.Request, next func(http.ResponseWriter, *http.Request)) {
+	if l.skip(r) {
+		next(w, r)
+		return
+	}
+	l.init()
+	aw := accessWriterPool.Get().(*accessWriter)
+	aw.ResponseWriter = w
+	// 通过 context 传递, 中间件或 http.TimeoutHandler 包装 ResponseWriter 后 router 仍能写入
+	rec := &accessRecord{log: l}
+	r = r.WithContext(context.WithValue(r.Context(), accessRecordKey{}, rec))
+	start := time.Now()
+	defer func() {
+		if aw.status == 0 {
+			aw.status = http.StatusOK
+		}
+		if l.SampleRate <= 0 || l.SampleRate >= 1 || aw.status >= 500 || rand.Float64() < l.SampleRate {
+			l.write(aw, rec, r, start, time.Since(start))
+		}
+		*aw = accessWriter{}
+		accessWriterPool.Put(aw)
+	}()
+	next(aw, r)
+}
+
+func (l *AccessLog) write(aw *accessWriter, rec *accessRecord, r *http.Request, start time.Time, latency time.Duration) {
+	rec.mu.Lock()
+	route, ip, user := rec.route, rec.ip, rec.user
+	rec.mu.Unlock()
+	if ip == "" {
+		ip = ClientIP(r)
+	}
+	requestID := aw.Header().Get("X-Request-Id")
+	if requestID == "" {
+		requestID = r.Header.Get("X-Request-Id")
+	}
+	switch l.Format {
+	case AccessLogCommon, AccessLogCombined:
+		if user == "" {
+			user = "-"
+		}
+		size := "-"
+		if aw.size > 0 {
+			size = strconv.FormatInt(aw.size, 10)
+		}
+		line := fmt.Sprintf("%s - %s [%s] %q %d %s", ip, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+			r.Method+" "+r.RequestURI+" "+r.Proto, aw.status, size)
+		if l.Format == AccessLogCombined {
+			line += fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())
+		}
+		l.mu.Lock()
+		io.WriteString(l.Output, line+"\n")
+		l.mu.Unlock()
+		return
+	}
+	e := l.json.Log()
+	f := l.fields
+	if f[FieldTime] {
+		e = e.Time(FieldTime, start)
+	}
+	if f[FieldMethod] {
+		e = e.Str(FieldMethod, r.Method)
+	}
+	if f[FieldPath] {
+		e = e.Str(FieldPath, r.URL.Path)
+	}
+	if f[FieldRoute] && route != "" {
+		e = e.Str(FieldRoute, route)
+	}
+	if f[FieldStatus] {
+		e = e.Int(FieldStatus, aw.status)
+	}
+	if f[FieldSize] {
+		e = e.Int64(FieldSize, aw.size)
+	}
+	if f[FieldLatency] {
+		e = e.Dur(FieldLatency, latency)
+	}
+	if f[FieldIP] {
+		e = e.Str(FieldIP, ip)
+	}
+	if f[FieldUserAgent] {
+		e = e.Str(FieldUserAgent, r.UserAgent())
+	}
+	if f[FieldUser] && user != "" {
+		e = e.Str(FieldUser, user)
+	}
+	if f[FieldRequestID] && requestID != "" {
+		e = e.Str(FieldRequestID, requestID)
+	}
+	if f[FieldReferer] && r.Referer() != "" {
+		e = e.Str(FieldReferer, r.Referer())
+	}
+	if f[FieldProto] {
+		e = e.Str(FieldProto, r.Proto)
+	}
+	if f[FieldHost] {
+		e = e.Str(FieldHost, r.Host)
+	}
+	e.Send()
+}
+
+var accessWriterPool = sync.Pool{New: func() any { return &accessWriter{} }}
+
+// accessWriter 记录状态码和响应大小
+type accessWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+type accessRecordKey struct{}
+
+// accessRecord 路由匹配后由 router 通过请求 context 写入路由模板、客户端 IP 和用户
+type accessRecord struct {
+	mu    sync.Mutex
+	log   *AccessLog
+	route string
+	ip    string
+	user  string
+}
+
+func (w *accessWriter) WriteHeader(code int) {
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *accessWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *accessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recordAccess 在处理函数执行后由 router 调用, 请求未经过 AccessLog 时不做处理
+func recordAccess(req *http.Request, x *X) {
+	rec, ok := req.Context().Value(accessRecordKey{}).(*accessRecord)
+	if !ok {
+		return
+	}
+	route, ip, user := x.Route(), x.GetRemoteIP(), ""
+	if rec.log.UserID != nil {
+		user = rec.log.UserID(x)
+	}
+	rec.mu.Lock()
+	rec.route, rec.ip, rec.user = route, ip, user
+	rec.mu.Unlock()
+}
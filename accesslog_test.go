@@ -0,0 +1,116 @@
+package vigo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAccessLogApp(t *testing.T, l *AccessLog) *Application {
+	app, err := NewServer(WithDocPath(""), WithAccessLog(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Router().Get("/users/{id}", func(x *X) {
+		x.Set("uid", "u-"+x.PathParams.Get("id"))
+		x.Header().Set("X-Request-Id", "req-1")
+		x.WriteHeader(http.StatusCreated)
+		x.WriteString("hello")
+	})
+	app.Router().Get("/fail", func(x *X) { x.WriteHeader(http.StatusInternalServerError) })
+	app.Router().Get("/healthz", func(x *X) {})
+	return app
+}
+
+func serveAccess(app *Application, path string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("User-Agent", "curl/8")
+	req.Header.Set("Referer", "https://example.com/")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	app := newAccessLogApp(t, &AccessLog{
+		Output:    &buf,
+		SkipPaths: []string{"/healthz"},
+		UserID:    func(x *X) string { s, _ := x.Get("uid").(string); return s },
+	})
+	serveAccess(app, "/users/7?x=1")
+	serveAccess(app, "/healthz")
+	serveAccess(app, "/missing")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"method": "GET", "path": "/users/7", "route": "/users/{id}", "status": float64(201), "size": float64(5),
+		"ip": "10.0.0.1", "user_agent": "curl/8", "user": "u-7", "request_id": "req-1",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+	if _, ok := entry["latency"]; !ok {
+		t.Error("missing latency")
+	}
+	if !strings.Contains(lines[1], `"status":404`) || strings.Contains(lines[1], `"route"`) {
+		t.Errorf("unexpected 404 entry %s", lines[1])
+	}
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	var buf bytes.Buffer
+	app := newAccessLogApp(t, &AccessLog{Format: AccessLogCombined, Output: &buf})
+	serveAccess(app, "/users/7")
+	re := regexp.MustCompile(`^10\.0\.0\.1 - - \[[^\]]+\] "GET /users/7 HTTP/1\.1" 201 5 "https://example\.com/" "curl/8"\n$`)
+	if !re.MatchString(buf.String()) {
+		t.Errorf("unexpected combined line %q", buf.String())
+	}
+
+	buf.Reset()
+	app = newAccessLogApp(t, &AccessLog{Format: AccessLogCommon, Output: &buf, Fields: []string{FieldStatus}})
+	serveAccess(app, "/fail")
+	if !strings.HasSuffix(buf.String(), `"GET /fail HTTP/1.1" 500 -`+"\n") {
+		t.Errorf("unexpected common line %q", buf.String())
+	}
+
+	buf.Reset()
+	app = newAccessLogApp(t, &AccessLog{Output: &buf, Fields: []string{FieldStatus, FieldRoute}, SampleRate: 1e-9})
+	for range 20 {
+		serveAccess(app, "/users/7")
+	}
+	serveAccess(app, "/fail")
+	if buf.String() != `{"route":"/fail","status":500}`+"\n" {
+		t.Errorf("sampling should keep only server errors, got %q", buf.String())
+	}
+}
+
+func TestAccessLogWrappedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	l := &AccessLog{
+		Output: &buf,
+		Fields: []string{FieldRoute, FieldStatus, FieldUser},
+		UserID: func(x *X) string { s, _ := x.Get("uid").(string); return s },
+	}
+	app := newAccessLogApp(t, l)
+	// 包装 ResponseWriter 的中间件不应丢失路由和用户
+	h := http.TimeoutHandler(app.router, time.Second, "timeout")
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	l.serve(httptest.NewRecorder(), req, h.ServeHTTP)
+	if buf.String() != `{"route":"/users/{id}","status":201,"user":"u-7"}`+"\n" {
+		t.Errorf("unexpected entry %q", buf.String())
+	}
+}
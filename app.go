@@ -8,6 +8,12 @@
 package vigo
 
 import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
 	"github.com/veypi/vigo/contrib/event"
 	"github.com/veypi/vigo/flags"
 	"github.com/veypi/vigo/logv"
@@ -21,6 +27,14 @@ type App[T any] interface {
 	Config() T
 	// 初始化函数
 	Init() error
+	// 注册关闭时执行的函数, 按注册的相反顺序执行
+	OnShutdown(name string, fn func(ctx context.Context) error)
+	// 注册开始关闭时立即执行的函数, 如将 readiness 置为不可用
+	BeforeShutdown(fn func())
+	// 添加监听地址及其路由, 如管理端口, 与主服务一起启动和关闭
+	Listen(name string, router Router, opts ...func(*Config))
+	// 添加模块, 按依赖顺序在 Init 之前初始化, 与服务一起启动和关闭
+	AddModule(modules ...Module)
 	Run() error
 }
 
@@ -34,10 +48,20 @@ func New[T any](name string, router Router, config T, init func() error) App[T]
 }
 
 type app[T any] struct {
-	router Router
+	router  Router
+	name    string
+	cfg     T
+	init    func() error
+	hooks   []shutdownHook
+	before  []func()
+	extra   []extraListener
+	modules []Module
+}
+
+type extraListener struct {
 	name   string
-	cfg    T
-	init   func() error
+	router Router
+	opts   []func(*Config)
 }
 
 func (a *app[T]) Router() Router {
@@ -59,19 +83,55 @@ func (a *app[T]) Init() error {
 	return nil
 }
 
+func (a *app[T]) OnShutdown(name string, fn func(ctx context.Context) error) {
+	a.hooks = append(a.hooks, shutdownHook{name: name, fn: fn})
+}
+
+func (a *app[T]) BeforeShutdown(fn func()) {
+	a.before = append(a.before, fn)
+}
+
+func (a *app[T]) Listen(name string, router Router, opts ...func(*Config)) {
+	a.extra = append(a.extra, extraListener{name: name, router: router, opts: opts})
+}
+
+func (a *app[T]) AddModule(modules ...Module) {
+	a.modules = append(a.modules, modules...)
+}
+
 func (a *app[T]) Run() error {
+	modules, err := sortModules(a.modules)
+	if err != nil {
+		return err
+	}
 	cmdMain := flags.New(a.Name(), "")
 	host := cmdMain.String("host", "0.0.0.0", "")
 	port := cmdMain.Int("p", 4000, "port")
 	configFile := cmdMain.String("f", "./dev.yaml", "the config file")
 	loggerLevel := cmdMain.String("l", "debug", "logger_level")
 	loggerPath := cmdMain.String("logger_path", "", "logger_path")
+	// http.Server 超时等设置, 对应参数 -server.read_timeout 和配置文件 server: 下的同名字段
+	httpCfg := &struct {
+		Server HTTPConfig `json:"server" yaml:"server"`
+	}{Server: DefaultHTTPConfig()}
+	if configKeys(a.Config())["server"] {
+		return errors.New("app config must not use the server key, it is reserved for http server settings")
+	}
+	if err := checkModuleNames(modules, a.Config(), httpCfg); err != nil {
+		return err
+	}
 	cmdCfg := cmdMain.SubCommand("gen", "generate cfg file")
 	cmdCfg.Command = func() error {
-		return flags.DumpCfg(*configFile, a.Config())
+		node, err := configNode(modules, a.Config(), httpCfg)
+		if err != nil {
+			return err
+		}
+		return flags.DumpCfg(*configFile, node)
 	}
 	cmdMain.Before = func() error {
 		flags.LoadCfg(*configFile, a.Config())
+		flags.LoadCfg(*configFile, httpCfg)
+		loadModuleConfigs(*configFile, modules)
 		cmdMain.Parse()
 		logv.SetLevel(logv.AssertFuncErr(logv.ParseLevel(*loggerLevel)))
 		if loggerPath != nil && *loggerPath != "" {
@@ -80,19 +140,137 @@ func (a *app[T]) Run() error {
 		return nil
 	}
 	cmdMain.AutoRegister(a.Config())
+	cmdMain.AutoRegister(httpCfg)
+	// 模块配置对应参数 -module.field 和配置文件 module: 下的字段
+	registerModuleFlags(cmdMain, modules)
 	cmdMain.Command = func() error {
-		err := a.init()
-		if err != nil {
+		if err := initModules(modules, a.Router()); err != nil {
 			return err
 		}
-		event.Start()
-		server, err := NewServer(WithHost(*host), WithPort(*port))
+		if err := a.Init(); err != nil {
+			return err
+		}
+		server, err := NewServer(WithHost(*host), WithPort(*port), WithHTTPConfig(httpCfg.Server))
 		if err != nil {
 			return err
 		}
 		server.SetRouter(a.Router())
+		for _, e := range a.extra {
+			l, err := server.AddListener(e.name, e.opts...)
+			if err != nil {
+				return err
+			}
+			l.SetRouter(e.router)
+		}
+		// 逆序执行: 先停止后台任务, 再执行自定义函数, 最后关闭配置中的数据库/Redis 等连接
+		registerClosers(server, a.Config())
+		for _, m := range modules {
+			if cfg := moduleConfig(m); cfg != nil {
+				registerClosers(server, cfg)
+			}
+		}
+		for _, fn := range a.before {
+			server.BeforeShutdown(fn)
+		}
+		for _, h := range a.hooks {
+			server.OnShutdown(h.name, h.fn)
+		}
+		// 服务创建成功后再启动后台任务, 监听失败时由 Shutdown 停止
+		event.Start()
+		// 模块在 event 之后按启动的相反顺序停止
+		if err := startModules(context.Background(), server, modules); err != nil {
+			return errors.Join(err, stopEvent(context.Background()), server.Shutdown(context.Background()))
+		}
+		server.OnShutdown("event", stopEvent)
 		return server.Run()
 	}
 	cmdMain.Parse()
 	return cmdMain.Run()
 }
+
+func stopEvent(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		event.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// configKeys 返回配置结构体顶层的参数名和配置项名, 即 json tag 和 yaml tag (未设置时为小写字段名), 含嵌入结构体的字段
+func configKeys(cfg any) map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(cfg)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return keys
+	}
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && ft.Kind() == reflect.Struct {
+				collect(ft)
+				continue
+			}
+			if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+				keys[name] = true
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			if name != "-" {
+				keys[name] = true
+			}
+		}
+	}
+	collect(t)
+	return keys
+}
+
+// registerClosers 为配置结构体中实现了 io.Closer 的字段注册关闭函数, 如 config.Database, config.Redis
+func registerClosers(server *Application, cfg any) {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() != reflect.Ptr && f.CanAddr() {
+			f = f.Addr()
+		}
+		if f.Kind() == reflect.Ptr && f.IsNil() {
+			continue
+		}
+		if c, ok := f.Interface().(io.Closer); ok {
+			server.OnShutdown(t.Field(i).Name, func(context.Context) error {
+				return c.Close()
+			})
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package vigo
+
+import (
+	"strings"
+	"testing"
+)
+
+type testAppConfig struct {
+	HTTPConfig
+	Name    string `json:"name" yaml:"app_name"`
+	Timeout int
+	Skip    string `json:"-" yaml:"-"`
+	secret  string
+}
+
+func TestConfigKeys(t *testing.T) {
+	keys := configKeys(&testAppConfig{})
+	for _, k := range []string{"read_timeout", "name", "app_name", "timeout"} {
+		if !keys[k] {
+			t.Errorf("missing key %s in %v", k, keys)
+		}
+	}
+	if keys["-"] || keys["skip"] || keys["secret"] || len(configKeys(nil)) != 0 {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestAppReservedServerKey(t *testing.T) {
+	// server 段保留给 HTTPConfig, 应用配置中不能再使用
+	cfg := &struct {
+		Server string `json:"server"`
+	}{}
+	err := New("demo", NewRouter(), cfg, nil).Run()
+	if err == nil || !strings.Contains(err.Error(), "server key") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -8,18 +8,30 @@
 package vigo
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
-	"fmt"
+	"net"
+	"os"
+	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
-var ipv4Regex = regexp.MustCompile(`^((25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\.){3}(25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])$`)
+var hostnameRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*\.?$`)
 
 type Config struct {
 	DocPath string `json:"doc_path,omitempty"`
-	Host    string `json:"host"`
-	Port    int    `json:"port"`
+	// Host 监听地址, 支持 IPv4/IPv6/主机名, unix:/run/app.sock 监听 Unix socket,
+	// fd:3 或 fd:name 使用 systemd socket activation 传入的 socket
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// UnixSocketMode Unix socket 文件权限, 默认 0660
+	UnixSocketMode os.FileMode `json:"unix_socket_mode,omitempty"`
 	// log file path
 	LoggerPath     string `json:"logger_path,omitempty"`
 	LoggerLevel    string `json:"logger_level,omitempty"`
@@ -29,17 +41,109 @@ type Config struct {
 	TlsCfg         *tls.Config
 	MaxConnections int
 	DisableReqLog  bool `json:"disable_req_log,omitempty"`
+	// AccessLog 访问日志, 设置后替代 DisableReqLog 控制的调试日志
+	AccessLog *AccessLog `json:"-"`
+	HTTPConfig
+	// BaseContext 为每个 listener 创建基础 context, 可用于注入全局值
+	BaseContext func(l net.Listener) context.Context `json:"-"`
+	// ConnContext 为每个连接派生 context, 可用于记录连接信息
+	ConnContext func(ctx context.Context, c net.Conn) context.Context `json:"-"`
+}
+
+// HTTPConfig http.Server 的超时、请求头限制、keep-alive 和 TLS 证书设置, 由 App.Run 注册为 server.* 参数和配置项
+type HTTPConfig struct {
+	// ReadHeaderTimeout 读取请求头的超时, 防止 slowloris
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout" yaml:"read_header_timeout" desc:"timeout for reading request headers"`
+	// ReadTimeout 读取整个请求 (含请求体) 的超时, 使用 src:"body" 流式读取请求体时改为限制每次读取的等待时间
+	ReadTimeout time.Duration `json:"read_timeout" yaml:"read_timeout" desc:"timeout for reading the entire request"`
+	// WriteTimeout 写响应的超时, SSE、x.Stream 和 x.File 等流式响应会自动取消
+	WriteTimeout time.Duration `json:"write_timeout" yaml:"write_timeout" desc:"timeout for writing the response"`
+	// IdleTimeout keep-alive 连接的空闲超时
+	IdleTimeout time.Duration `json:"idle_timeout" yaml:"idle_timeout" desc:"keep-alive idle timeout"`
+	// MaxHeaderBytes 请求头最大字节数
+	MaxHeaderBytes int `json:"max_header_bytes" yaml:"max_header_bytes" desc:"max request header bytes"`
+	// DisableKeepAlives 关闭 keep-alive, 每个请求后断开连接
+	DisableKeepAlives bool `json:"disable_keep_alives" yaml:"disable_keep_alives" desc:"disable http keep-alive"`
+	// ShutdownTimeout 优雅关闭时等待请求处理完成及执行关闭函数的最长时间
+	ShutdownTimeout time.Duration `json:"shutdown_timeout" yaml:"shutdown_timeout" desc:"graceful shutdown timeout"`
+	// ShutdownDelay 开始关闭后继续接收请求的时间, 期间 readiness 返回不可用, 便于负载均衡摘除实例
+	ShutdownDelay time.Duration `json:"shutdown_delay,omitempty" yaml:"shutdown_delay,omitempty" desc:"keep serving after shutdown starts so load balancers can deregister"`
+	// TrustedProxies 可信代理的 CIDR 或 IP, 只采用来自这些地址的 X-Forwarded-*/Forwarded 头
+	TrustedProxies []string `json:"trusted_proxies,omitempty" yaml:"trusted_proxies,omitempty" desc:"trusted proxy cidrs for forwarded headers"`
+	// ForwardedHeader 可信代理追加客户端地址的头, X-Forwarded-For 或 Forwarded, 只解析该头
+	ForwardedHeader string `json:"forwarded_header,omitempty" yaml:"forwarded_header,omitempty" desc:"header set by trusted proxies: X-Forwarded-For or Forwarded"`
+	// H2C 不使用 TLS 时支持 HTTP/2 (h2c), 包括 prior knowledge 和 Upgrade 两种方式, 用于 TLS 由 sidecar 终止的场景
+	H2C bool `json:"h2c,omitempty" yaml:"h2c,omitempty" desc:"enable http/2 over cleartext (h2c)"`
+	// TLSCertFile/TLSKeyFile 证书和私钥文件, 设置后启用 https, 文件更新后自动重新加载
+	TLSCertFile string `json:"tls_cert_file,omitempty" yaml:"tls_cert_file,omitempty" desc:"tls certificate file"`
+	TLSKeyFile  string `json:"tls_key_file,omitempty" yaml:"tls_key_file,omitempty" desc:"tls private key file"`
+	// TLSClientCAFile 客户端 CA 文件, 设置后要求客户端证书 (mTLS), 可通过 x.ClientCert() 获取
+	TLSClientCAFile string `json:"tls_client_ca_file,omitempty" yaml:"tls_client_ca_file,omitempty" desc:"client ca file for mutual tls"`
+	// TLSClientCertOptional 客户端可以不提供证书, 提供时仍然校验
+	TLSClientCertOptional bool `json:"tls_client_cert_optional,omitempty" yaml:"tls_client_cert_optional,omitempty" desc:"do not require client certificates"`
+}
+
+// DefaultHTTPConfig 默认的超时和限制
+func DefaultHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+		ShutdownTimeout:   10 * time.Second,
+	}
 }
 
+// MarshalYAML 时长输出为 10s 这样的格式, 便于生成的配置文件阅读和修改
+func (c HTTPConfig) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		f := v.Field(i)
+		if name == "" || name == "-" || (opts == "omitempty" && f.IsZero()) {
+			continue
+		}
+		value := &yaml.Node{}
+		if d, ok := f.Interface().(time.Duration); ok {
+			value = &yaml.Node{Kind: yaml.ScalarNode, Value: d.String()}
+		} else if err := value.Encode(f.Interface()); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, value)
+	}
+	return node, nil
+}
+
+// Url 返回监听地址, IPv6 地址带方括号, Unix socket 和继承的 socket 返回 Host 本身
 func (c *Config) Url() string {
-	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+	if strings.HasPrefix(c.Host, unixPrefix) || strings.HasPrefix(c.Host, fdPrefix) {
+		return c.Host
+	}
+	return net.JoinHostPort(strings.Trim(c.Host, "[]"), strconv.Itoa(c.Port))
 }
 
 func (c *Config) IsValid() error {
-	if !ipv4Regex.MatchString(c.Host) {
+	if path, ok := strings.CutPrefix(c.Host, unixPrefix); ok {
+		if path == "" {
+			return errors.New("invalid unix socket path")
+		}
+		return nil
+	}
+	if strings.HasPrefix(c.Host, fdPrefix) {
+		return nil
+	}
+	// 空 Host 监听所有地址
+	if host := strings.Trim(c.Host, "[]"); host != "" && net.ParseIP(host) == nil && !hostnameRegex.MatchString(host) {
 		return errors.New("invalid host")
 	}
-	if c.Port <= 0 || c.Port > 65535 {
+	// tcp 监听必须指定端口, 不使用 0 端口随机监听
+	if c.Port == 0 {
+		return errors.New("port is required")
+	}
+	if c.Port < 0 || c.Port > 65535 {
 		return errors.New("invalid port")
 	}
 	return nil
@@ -51,6 +155,43 @@ func WithTls(cfg *tls.Config) func(*Config) {
 	}
 }
 
+// WithTrustedProxies 设置可信代理, 如 WithTrustedProxies("10.0.0.0/8", "127.0.0.1")
+func WithTrustedProxies(cidrs ...string) func(*Config) {
+	return func(c *Config) {
+		c.TrustedProxies = cidrs
+	}
+}
+
+// WithForwardedHeader 设置可信代理使用的头, vigo.HeaderXForwardedFor (默认) 或 vigo.HeaderForwarded
+func WithForwardedHeader(header string) func(*Config) {
+	return func(c *Config) {
+		c.ForwardedHeader = header
+	}
+}
+
+// WithH2C 启用 h2c, 配置 TLS 时不生效 (TLS 下默认支持 HTTP/2)
+func WithH2C() func(*Config) {
+	return func(c *Config) {
+		c.H2C = true
+	}
+}
+
+// WithTLSFiles 从证书和私钥文件启用 https, 文件更新后自动重新加载
+func WithTLSFiles(certFile, keyFile string) func(*Config) {
+	return func(c *Config) {
+		c.TLSCertFile = certFile
+		c.TLSKeyFile = keyFile
+	}
+}
+
+// WithClientCA 设置客户端 CA 文件启用 mTLS, optional 为 true 时客户端可以不提供证书
+func WithClientCA(caFile string, optional bool) func(*Config) {
+	return func(c *Config) {
+		c.TLSClientCAFile = caFile
+		c.TLSClientCertOptional = optional
+	}
+}
+
 func WithDocPath(path string) func(*Config) {
 	return func(c *Config) {
 		c.DocPath = path
@@ -69,6 +210,27 @@ func WithPort(port int) func(*Config) {
 	}
 }
 
+// WithAccessLog 设置访问日志
+func WithAccessLog(l *AccessLog) func(*Config) {
+	return func(c *Config) {
+		c.AccessLog = l
+	}
+}
+
+// WithMaxConnections 限制同时处理的连接数
+func WithMaxConnections(n int) func(*Config) {
+	return func(c *Config) {
+		c.MaxConnections = n
+	}
+}
+
+// WithUnixSocketMode 设置 Unix socket 文件权限, 配合 WithHost("unix:/run/app.sock") 使用
+func WithUnixSocketMode(mode os.FileMode) func(*Config) {
+	return func(c *Config) {
+		c.UnixSocketMode = mode
+	}
+}
+
 func WithLoggerPath(path string) func(*Config) {
 	return func(c *Config) {
 		c.LoggerPath = path
@@ -86,3 +248,69 @@ func WithPrettyLog() func(*Config) {
 		c.PrettyLog = true
 	}
 }
+
+func WithShutdownTimeout(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.ShutdownTimeout = d
+	}
+}
+
+func WithShutdownDelay(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.ShutdownDelay = d
+	}
+}
+
+func WithHTTPConfig(h HTTPConfig) func(*Config) {
+	return func(c *Config) {
+		c.HTTPConfig = h
+	}
+}
+
+func WithReadHeaderTimeout(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.ReadHeaderTimeout = d
+	}
+}
+
+func WithReadTimeout(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.ReadTimeout = d
+	}
+}
+
+func WithWriteTimeout(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.WriteTimeout = d
+	}
+}
+
+func WithIdleTimeout(d time.Duration) func(*Config) {
+	return func(c *Config) {
+		c.IdleTimeout = d
+	}
+}
+
+func WithMaxHeaderBytes(n int) func(*Config) {
+	return func(c *Config) {
+		c.MaxHeaderBytes = n
+	}
+}
+
+func WithDisableKeepAlives() func(*Config) {
+	return func(c *Config) {
+		c.DisableKeepAlives = true
+	}
+}
+
+func WithBaseContext(fn func(l net.Listener) context.Context) func(*Config) {
+	return func(c *Config) {
+		c.BaseContext = fn
+	}
+}
+
+func WithConnContext(fn func(ctx context.Context, c net.Conn) context.Context) func(*Config) {
+	return func(c *Config) {
+		c.ConnContext = fn
+	}
+}
@@ -0,0 +1,369 @@
+//
+// main.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+// vigogen 为请求结构体生成免反射的 ParseVigo 方法, X.Parse 检测到后直接调用
+//
+// 用法:
+//
+//	//go:generate go run github.com/veypi/vigo/cmd/vigogen -type=UserReq,ListReq
+//
+// 生成文件为 <源文件>_vigo.go, 源文件为测试文件时生成 <源文件>_vigo_test.go
+// string/bool/int/uint/float 及其指针类型直接赋值, 其余类型回退到单字段反射解析
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of struct names; required")
+	output    = flag.String("output", "", "output file name; default <src>_vigo.go")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: vigogen -type=A,B [-output file] [file.go]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	src := os.Getenv("GOFILE")
+	if flag.NArg() > 0 {
+		src = flag.Arg(0)
+	}
+	if src == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(src, strings.Split(*typeNames, ","), *output); err != nil {
+		fmt.Fprintf(os.Stderr, "vigogen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(src string, names []string, out string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, src, nil, 0)
+	if err != nil {
+		return err
+	}
+	structs := make(map[string]*ast.StructType)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ts, ok := n.(*ast.TypeSpec); ok {
+			if st, ok := ts.Type.(*ast.StructType); ok {
+				structs[ts.Name.Name] = st
+			}
+		}
+		return true
+	})
+
+	g := &generator{pkg: file.Name.Name}
+	if g.pkg != "vigo" {
+		g.qualifier = "vigo."
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		st, ok := structs[name]
+		if !ok {
+			return fmt.Errorf("struct %s not found in %s", name, src)
+		}
+		if err := g.genStruct(name, st); err != nil {
+			return err
+		}
+	}
+
+	code, err := format.Source(g.file())
+	if err != nil {
+		return fmt.Errorf("format generated code: %w", err)
+	}
+	if out == "" {
+		out = outputName(src)
+	}
+	return os.WriteFile(out, code, 0644)
+}
+
+func outputName(src string) string {
+	dir, base := filepath.Split(src)
+	if strings.HasSuffix(base, "_test.go") {
+		return filepath.Join(dir, strings.TrimSuffix(base, "_test.go")+"_vigo_test.go")
+	}
+	return filepath.Join(dir, strings.TrimSuffix(base, ".go")+"_vigo.go")
+}
+
+type generator struct {
+	pkg         string
+	qualifier   string
+	usesStrconv bool
+	body        bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+func (g *generator) file() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by vigogen. DO NOT EDIT.\n\npackage %s\n\n", g.pkg)
+	imports := []string{}
+	if g.usesStrconv {
+		imports = append(imports, `"strconv"`)
+	}
+	if g.qualifier != "" {
+		if len(imports) > 0 {
+			imports = append(imports, "")
+		}
+		imports = append(imports, `"github.com/veypi/vigo"`)
+	}
+	if len(imports) > 0 {
+		fmt.Fprintf(&buf, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	buf.Write(g.body.Bytes())
+	return buf.Bytes()
+}
+
+// field 与 vigo.getOrCreateTypeInfo 的解析规则保持一致
+type field struct {
+	goName     string
+	name       string
+	source     string
+	defaultVal *string
+	typ        string // builtin scalar name, empty if not supported directly
+	pointer    bool
+}
+
+func (g *generator) genStruct(name string, st *ast.StructType) error {
+	g.printf("// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致\n")
+	g.printf("func (r *%s) ParseVigo(x *%sX) error {\n", name, g.qualifier)
+	g.printf("c, err := x.NewParseContext(r)\nif err != nil {\nreturn err\n}\n")
+	jsonDone := false
+	for _, astField := range st.Fields.List {
+		// 匿名字段不解析
+		if len(astField.Names) == 0 {
+			continue
+		}
+		var tag reflect.StructTag
+		if astField.Tag != nil {
+			raw, err := strconv.Unquote(astField.Tag.Value)
+			if err != nil {
+				return err
+			}
+			tag = reflect.StructTag(raw)
+		}
+		for _, ident := range astField.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			f, ok := parseField(ident.Name, astField.Type, tag)
+			if !ok {
+				continue
+			}
+			if f.source == "json" {
+				if !jsonDone {
+					g.printf("if err := c.JSON(); err != nil {\nreturn err\n}\n")
+					jsonDone = true
+				}
+				continue
+			}
+			g.genField(f)
+		}
+	}
+	g.printf("return nil\n}\n\n")
+	return nil
+}
+
+func parseField(goName string, expr ast.Expr, tag reflect.StructTag) (*field, bool) {
+	parseTag := tag.Get("src")
+	jsonTag := tag.Get("json")
+	if idx := strings.Index(jsonTag, ","); idx != -1 {
+		jsonTag = jsonTag[:idx]
+	}
+	if jsonTag == "-" {
+		return nil, false
+	}
+	f := &field{goName: goName}
+	if v, ok := tag.Lookup("default"); ok {
+		f.defaultVal = &v
+	}
+	if parseTag == "" {
+		parseTag = "json"
+	}
+	f.name = jsonTag
+	if f.name == "" {
+		f.name = goName
+	}
+	if strings.Contains(parseTag, "@") {
+		parts := strings.Split(parseTag, "@")
+		parseTag = parts[0]
+		if len(parts) > 1 {
+			f.name = parts[1]
+		}
+	}
+	switch {
+	case parseTag == "form":
+		f.source = "Form"
+	case parseTag == "query":
+		f.source = "Query"
+	case strings.HasPrefix(parseTag, "header"):
+		f.source = "Header"
+	case strings.HasPrefix(parseTag, "path"):
+		f.source = "Path"
+	case parseTag == "body":
+		// 流式请求体交给反射赋值
+		f.source = "Body"
+		return f, true
+	default:
+		f.source = "json"
+	}
+
+	if star, ok := expr.(*ast.StarExpr); ok {
+		f.pointer = true
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok && scalarKind(ident.Name) != "" && tag.Get("style") == "" {
+		f.typ = ident.Name
+	}
+	return f, true
+}
+
+func scalarKind(typ string) string {
+	switch typ {
+	case "string":
+		return "string"
+	case "bool":
+		return "bool"
+	case "int", "int8", "int16", "int32", "int64":
+		return "int"
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return "uint"
+	case "float32", "float64":
+		return "float"
+	}
+	return ""
+}
+
+func (g *generator) genField(f *field) {
+	var def string
+	hasDefault := f.defaultVal != nil && *f.defaultVal != ""
+	if f.typ != "" && hasDefault {
+		var ok bool
+		if def, ok = defaultLiteral(f.typ, *f.defaultVal); !ok {
+			// 默认值无法在生成时确定, 交由运行时报错
+			f.typ = ""
+		}
+	}
+	if f.typ == "" {
+		g.printf("if err := c.Field(%q); err != nil {\nreturn err\n}\n", f.goName)
+		return
+	}
+
+	target := "r." + f.goName
+	if f.pointer {
+		target = "*r." + f.goName
+	}
+	g.printf("if v, ok := c.%s(%q); ok {\n", f.source, f.name)
+	g.alloc(f)
+	switch scalarKind(f.typ) {
+	case "string":
+		g.printf("%s = v\n", target)
+	case "bool":
+		g.printf("b, err := %sParseBool(v)\nif err != nil {\nreturn c.Err(%q, err)\n}\n%s = b\n", g.qualifier, f.name, target)
+	case "int":
+		g.usesStrconv = true
+		g.printf("n, err := strconv.ParseInt(v, 10, 64)\nif err != nil {\nreturn c.Err(%q, err)\n}\n%s = %s(n)\n", f.name, target, f.typ)
+	case "uint":
+		g.usesStrconv = true
+		g.printf("n, err := strconv.ParseUint(v, 10, 64)\nif err != nil {\nreturn c.Err(%q, err)\n}\n%s = %s(n)\n", f.name, target, f.typ)
+	case "float":
+		g.usesStrconv = true
+		g.printf("n, err := strconv.ParseFloat(v, 64)\nif err != nil {\nreturn c.Err(%q, err)\n}\n%s = %s(n)\n", f.name, target, f.typ)
+	}
+	switch {
+	case hasDefault:
+		g.printf("} else {\n")
+		g.alloc(f)
+		g.printf("%s = %s\n", target, def)
+	case f.defaultVal == nil && !f.pointer:
+		g.printf("} else {\nreturn c.Missing(%q)\n", f.name)
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) alloc(f *field) {
+	if f.pointer {
+		g.printf("if r.%s == nil {\nr.%s = new(%s)\n}\n", f.goName, f.goName, f.typ)
+	}
+}
+
+// defaultLiteral 按 setValueFromString 的规则解析默认值, 返回 Go 字面量
+func defaultLiteral(typ, val string) (string, bool) {
+	switch scalarKind(typ) {
+	case "string":
+		return strconv.Quote(val), true
+	case "bool":
+		b, err := strconv.ParseBool(val)
+		return strconv.FormatBool(b), err == nil
+	case "int":
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || !fitsInt(typ, n) {
+			return "", false
+		}
+		return fmt.Sprintf("%s(%d)", typ, n), true
+	case "uint":
+		n, err := strconv.ParseUint(val, 10, 64)
+		if err != nil || !fitsUint(typ, n) {
+			return "", false
+		}
+		return fmt.Sprintf("%s(%d)", typ, n), true
+	case "float":
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || (typ == "float32" && math.Abs(n) > math.MaxFloat32) {
+			return "", false
+		}
+		return fmt.Sprintf("%s(%s)", typ, strconv.FormatFloat(n, 'g', -1, 64)), true
+	}
+	return "", false
+}
+
+func fitsInt(typ string, n int64) bool {
+	switch typ {
+	case "int8":
+		return n >= math.MinInt8 && n <= math.MaxInt8
+	case "int16":
+		return n >= math.MinInt16 && n <= math.MaxInt16
+	case "int32", "int":
+		// int 按 32 位平台保守处理
+		return n >= math.MinInt32 && n <= math.MaxInt32
+	}
+	return true
+}
+
+func fitsUint(typ string, n uint64) bool {
+	switch typ {
+	case "uint8":
+		return n <= math.MaxUint8
+	case "uint16":
+		return n <= math.MaxUint16
+	case "uint32", "uint":
+		return n <= math.MaxUint32
+	}
+	return true
+}
@@ -7,13 +7,21 @@ package common
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
+	"reflect"
 
 	"github.com/veypi/vigo"
+	"github.com/veypi/vigo/logv"
 )
 
+// JsonResponse 写出处理函数的返回值, 设置了 vigo.EnvelopeKey 时按其包装
 func JsonResponse(x *vigo.X, data any) error {
+	if env := x.Envelope(); env != nil && wrappable(data) {
+		status, body := env.Wrap(x, data, nil)
+		x.WriteHeader(status)
+		return x.JSON(body)
+	}
 	x.WriteHeader(200)
 	switch v := data.(type) {
 	case []byte:
@@ -29,15 +37,28 @@ func JsonResponse(x *vigo.X, data any) error {
 	return x.JSON(data)
 }
 
+// JsonErrorResponse 写出错误, 只返回 vigo.Error 的公开信息, 5xx 错误的内部信息和调用栈记录到日志
 func JsonErrorResponse(x *vigo.X, err error) error {
+	var e *vigo.Error
+	isErr := errors.As(err, &e)
+	if isErr {
+		logServerError(x, e, err)
+	}
+	if env := x.Envelope(); env != nil {
+		status, body := env.Wrap(x, nil, err)
+		x.WriteHeader(status)
+		return x.JSON(body)
+	}
 	code := 400
-	if e, ok := err.(*vigo.Error); ok {
-		code = e.Code
-		if code > 999 {
-			code, _ = strconv.Atoi(strconv.Itoa(code)[:3])
+	if isErr {
+		x.WriteHeader(e.Status())
+		resp := map[string]any{"code": e.Code, "message": x.ErrorMessage(e)}
+		if len(e.Details) > 0 {
+			resp["details"] = e.Details
+		}
+		if len(e.Fields) > 0 {
+			resp["fields"] = e.Fields
 		}
-		x.WriteHeader(code)
-		resp := map[string]any{"code": e.Code, "message": e.Message}
 		b, _ := json.Marshal(resp)
 		_, err := x.Write(b)
 		return err
@@ -48,3 +69,22 @@ func JsonErrorResponse(x *vigo.X, err error) error {
 	_, err = x.Write(b)
 	return err
 }
+
+// logServerError 5xx 错误记录完整信息和调用栈
+func logServerError(x *vigo.X, e *vigo.Error, err error) {
+	if e.Status() >= 500 {
+		logv.Error().Err(logv.Detailed(err)).Str("stack", e.StackString()).Msg(x.Request.RequestURI)
+	}
+}
+
+// wrappable 原始字节和流式响应不包装
+func wrappable(data any) bool {
+	if _, ok := data.([]byte); ok {
+		return false
+	}
+	if data == nil {
+		return true
+	}
+	k := reflect.TypeOf(data).Kind()
+	return k != reflect.Chan && k != reflect.Func
+}
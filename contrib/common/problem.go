@@ -0,0 +1,73 @@
+//
+// problem.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/veypi/vigo"
+)
+
+// ProblemTypeBase 设置后 type 为 ProblemTypeBase + 错误码, 如 https://api.example.com/errors/40401,
+// 否则为 about:blank
+var ProblemTypeBase = ""
+
+// ProblemField 字段校验错误, 作为 errors 扩展成员输出
+type ProblemField struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// ProblemErrorResponse 按 RFC 9457 写出 application/problem+json 错误
+//
+//	router.After(common.JsonResponse, common.ProblemErrorResponse)
+//
+// 5 位错误码取前 3 位作为 status, 错误码作为 code 扩展成员,
+// Fields 输出为 errors 数组, Details 合并为扩展成员
+func ProblemErrorResponse(x *vigo.X, err error) error {
+	status, code, detail := http.StatusBadRequest, http.StatusBadRequest, err.Error()
+	problem := map[string]any{}
+	var e *vigo.Error
+	if errors.As(err, &e) {
+		logServerError(x, e, err)
+		status, code, detail = e.Status(), e.Code, x.ErrorMessage(e)
+		for k, v := range e.Details {
+			problem[k] = v
+		}
+		if len(e.Fields) > 0 {
+			fields := make([]ProblemField, 0, len(e.Fields))
+			for k, v := range e.Fields {
+				fields = append(fields, ProblemField{Field: k, Detail: v})
+			}
+			sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+			problem["errors"] = fields
+		}
+	}
+	typ := "about:blank"
+	if ProblemTypeBase != "" {
+		typ = ProblemTypeBase + strconv.Itoa(code)
+	}
+	problem["type"] = typ
+	problem["title"] = http.StatusText(status)
+	problem["status"] = status
+	problem["detail"] = detail
+	problem["instance"] = x.Request.URL.Path
+	problem["code"] = code
+	if id := x.Request.Header.Get("X-Request-Id"); id != "" {
+		problem["request_id"] = id
+	}
+	b, _ := json.Marshal(problem)
+	x.Header().Set("Content-Type", "application/problem+json")
+	x.WriteHeader(status)
+	_, err = x.Write(b)
+	return err
+}
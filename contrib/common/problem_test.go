@@ -0,0 +1,62 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/veypi/vigo"
+)
+
+func TestProblemErrorResponse(t *testing.T) {
+	r := vigo.NewRouter()
+	r.After(JsonResponse, ProblemErrorResponse)
+	r.Get("/users/{id}", func(x *vigo.X) (any, error) {
+		return nil, vigo.ErrResourceNotFound.WithArgs("user").WithDetail("id", x.PathParams.Get("id"))
+	})
+	r.Post("/users", func(x *vigo.X) (any, error) {
+		return nil, vigo.ErrInvalidArg.WithField("name", "required").WithField("age", "too small")
+	})
+	r.Get("/plain", func(x *vigo.X) (any, error) { return nil, errors.New("boom") })
+
+	do := func(method, path string) (*httptest.ResponseRecorder, map[string]any) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		var m map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+			t.Fatalf("%s: %v %q", path, err, w.Body.String())
+		}
+		return w, m
+	}
+
+	w, m := do(http.MethodGet, "/users/7")
+	if w.Code != 404 || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	want := map[string]any{
+		"type": "about:blank", "title": "Not Found", "status": 404.0, "detail": "resource not found: user",
+		"instance": "/users/7", "code": 40401.0, "id": "7",
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("%s = %v, want %v", k, m[k], v)
+		}
+	}
+
+	ProblemTypeBase = "https://example.com/errors/"
+	defer func() { ProblemTypeBase = "" }()
+	w, m = do(http.MethodPost, "/users")
+	if w.Code != 400 || m["type"] != "https://example.com/errors/40001" {
+		t.Errorf("unexpected problem %d %v", w.Code, m)
+	}
+	fields, _ := m["errors"].([]any)
+	if len(fields) != 2 || fields[0].(map[string]any)["field"] != "age" || fields[1].(map[string]any)["detail"] != "required" {
+		t.Errorf("unexpected field errors %v", m["errors"])
+	}
+
+	if w, m := do(http.MethodGet, "/plain"); w.Code != 400 || m["detail"] != "boom" || m["title"] != "Bad Request" {
+		t.Errorf("unexpected plain problem %d %v", w.Code, m)
+	}
+}
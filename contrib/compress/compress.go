@@ -0,0 +1,264 @@
+//
+// compress.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+// Package compress 按 Accept-Encoding 压缩响应
+//
+//	router.Use(compress.Handler)
+//	router.Use(compress.New(compress.Config{MinSize: 512, ContentTypes: []string{"application/json"}}))
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/veypi/vigo"
+)
+
+// DefaultContentTypes 默认压缩的内容类型前缀, 图片/视频/压缩包等已压缩的类型不在其中
+var DefaultContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/x-ndjson",
+	"application/javascript",
+	"application/xml",
+	"application/yaml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// Config 压缩配置
+type Config struct {
+	// Level 压缩级别, 0 使用默认级别, 其余取值范围为 gzip.HuffmanOnly 到 gzip.BestCompression
+	Level int
+	// MinSize 小于该字节数的响应不压缩, 0 使用默认值 1024
+	MinSize int
+	// ContentTypes 允许压缩的内容类型前缀, 为空时使用 DefaultContentTypes
+	ContentTypes []string
+}
+
+// Handler 默认配置的压缩中间件
+var Handler = New(Config{})
+
+// New 创建压缩中间件
+//
+// 响应在达到 MinSize 前先缓冲, 之后根据状态码和 Content-Type 决定是否压缩.
+// 处理函数在此之前调用 Flush (SSE/流式输出), 或已设置 Content-Encoding 时直接透传;
+// Level 无效时 panic
+func New(cfg Config) func(*vigo.X) {
+	if cfg.Level == 0 {
+		cfg.Level = gzip.DefaultCompression
+	}
+	if cfg.Level < gzip.HuffmanOnly || cfg.Level > gzip.BestCompression {
+		panic(fmt.Errorf("compress: invalid level %d", cfg.Level))
+	}
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 1024
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultContentTypes
+	}
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, cfg.Level)
+			return w
+		}},
+		"deflate": {New: func() any {
+			w, _ := zlib.NewWriterLevel(io.Discard, cfg.Level)
+			return w
+		}},
+	}
+	return func(x *vigo.X) {
+		if x.Request.Method == http.MethodHead {
+			return
+		}
+		orig := x.ResponseWriter()
+		addVary(orig.Header(), "Accept-Encoding")
+		encoding := negotiate(x.Request.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			return
+		}
+		cw := &compressWriter{ResponseWriter: orig, cfg: &cfg, encoding: encoding, pool: pools[encoding]}
+		x.SetResponseWriter(cw)
+		defer x.SetResponseWriter(orig)
+		x.Next()
+		cw.close()
+	}
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter 缓冲到 MinSize 后决定是否压缩
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *Config
+	encoding string
+	pool     *sync.Pool
+	enc      encoder
+	buf      bytes.Buffer
+	status   int
+	decided  bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+	// 无响应体的状态码直接透传
+	if code == http.StatusNoContent || code == http.StatusNotModified || code < 200 {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		if w.buf.Len()+len(p) < w.cfg.MinSize {
+			return w.buf.Write(p)
+		}
+		w.buf.Write(p)
+		if err := w.decide(w.compressible()); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush 未决定前调用说明是流式输出, 不再压缩
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) compressible() bool {
+	h := w.ResponseWriter.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	// 3xx 和 206 不压缩
+	if w.status == http.StatusPartialContent || (w.status >= 300 && w.status < 400) {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(w.buf.Bytes())
+		h.Set("Content-Type", ct)
+	}
+	ct = strings.ToLower(ct)
+	if strings.HasPrefix(ct, "text/event-stream") {
+		return false
+	}
+	for _, prefix := range w.cfg.ContentTypes {
+		if strings.HasPrefix(ct, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// decide 写出响应头和已缓冲的数据
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	if compress {
+		h := w.ResponseWriter.Header()
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		w.enc = w.pool.Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		// 小于 MinSize, 原样输出
+		w.decide(false)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(io.Discard)
+		w.pool.Put(w.enc)
+		w.enc = nil
+	}
+}
+
+// negotiate 按 q 值选择 gzip 或 deflate, 相同时优先 gzip
+func negotiate(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		if name == "*" {
+			name = "gzip"
+		}
+		if (name != "gzip" && name != "deflate") || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+func addVary(h http.Header, key string) {
+	for _, v := range h.Values("Vary") {
+		for _, item := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), key) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", key)
+}
@@ -0,0 +1,136 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/veypi/vigo"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"gzip":                      "gzip",
+		"deflate, gzip":             "gzip",
+		"gzip;q=0.5, deflate":       "deflate",
+		"br, identity":              "",
+		"*":                         "gzip",
+		"gzip;q=0, deflate;q=0":     "",
+		"GZIP;q=0.8, deflate;q=0.1": "gzip",
+	}
+	for header, want := range cases {
+		if got := negotiate(header); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	big := strings.Repeat("hello vigo ", 200)
+	r := vigo.NewRouter()
+	r.Use(New(Config{MinSize: 100}))
+	r.Get("/text", func(x *vigo.X) {
+		x.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		x.Write([]byte(big))
+	})
+	r.Get("/small", func(x *vigo.X) {
+		x.Header().Set("Content-Type", "text/plain")
+		x.Write([]byte("hi"))
+	})
+	r.Get("/png", func(x *vigo.X) {
+		x.Header().Set("Content-Type", "image/png")
+		x.Write([]byte(big))
+	})
+	r.Get("/encoded", func(x *vigo.X) {
+		x.Header().Set("Content-Type", "text/plain")
+		x.Header().Set("Content-Encoding", "br")
+		x.Write([]byte(big))
+	})
+	r.Get("/sse", func(x *vigo.X) {
+		x.Header().Set("Content-Type", "text/event-stream")
+		x.Write([]byte("data: 1\n\n"))
+		x.Flush()
+		x.Write([]byte(big))
+	})
+	r.Get("/nocontent", func(x *vigo.X) {
+		x.WriteHeader(http.StatusNoContent)
+	})
+
+	do := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/text", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected gzip response, got %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(zr); string(b) != big {
+		t.Errorf("gzip body mismatch, got %d bytes", len(b))
+	}
+
+	w = do("/text", "deflate")
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("expected deflate response, got %v", w.Header())
+	}
+	fr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(fr); string(b) != big {
+		t.Errorf("deflate body mismatch, got %d bytes", len(b))
+	}
+
+	// 复用池中的 writer
+	if w := do("/text", "gzip"); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("expected pooled gzip response, got %v", w.Header())
+	}
+
+	for _, path := range []string{"/small", "/png", "/sse"} {
+		w := do(path, "gzip")
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s should not be compressed, got %v", path, w.Header())
+		}
+		if w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s expected Vary header, got %v", path, w.Header())
+		}
+	}
+	if w := do("/sse", "gzip"); !w.Flushed || !strings.HasPrefix(w.Body.String(), "data: 1") {
+		t.Errorf("expected sse to pass through, got %q", w.Body.String())
+	}
+	if w := do("/encoded", "gzip"); w.Header().Get("Content-Encoding") != "br" || w.Body.String() != big {
+		t.Errorf("expected existing encoding to be kept, got %v", w.Header())
+	}
+	if w := do("/text", ""); w.Header().Get("Content-Encoding") != "" || w.Body.String() != big {
+		t.Errorf("expected identity response, got %v", w.Header())
+	}
+	if w := do("/nocontent", "gzip"); w.Code != http.StatusNoContent || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected 204 passthrough, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestInvalidLevel(t *testing.T) {
+	for _, level := range []int{gzip.HuffmanOnly, gzip.BestSpeed, gzip.BestCompression} {
+		New(Config{Level: level})
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid level")
+		}
+	}()
+	New(Config{Level: 42})
+}
@@ -10,6 +10,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -23,11 +24,26 @@ type Database struct {
 	Prefix string `json:"prefix"`
 	Type   string `json:"type"`
 	DSN    string `json:"dsn"`
+
+	mu     sync.RWMutex
 	client *gorm.DB
+	closed bool
 }
 
+// Client 返回连接池, 首次调用时连接; Close 之后返回已关闭的连接池, 不会重新连接
 func (d *Database) Client() *gorm.DB {
+	d.mu.RLock()
+	client := d.client
+	d.mu.RUnlock()
+	if client != nil {
+		return client
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	if d.client == nil {
+		if d.closed {
+			panic(errors.New("数据库连接已关闭"))
+		}
 		var dialect gorm.Dialector
 		switch d.Type {
 		case "mysql":
@@ -54,5 +70,25 @@ func (d *Database) Client() *gorm.DB {
 }
 
 func (d *Database) SetClient(db *gorm.DB) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.client = db
 }
+
+// Close 关闭数据库连接池, 未连接或已关闭时不做处理
+func (d *Database) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	if d.client == nil {
+		return nil
+	}
+	db, err := d.client.DB()
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
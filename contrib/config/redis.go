@@ -8,6 +8,7 @@
 package config
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/alicebob/miniredis/v2"
@@ -19,17 +20,32 @@ type Redis struct {
 	Password string `json:"password"`
 	DB       int    `json:"db"`
 
-	once   sync.Once
+	mu     sync.RWMutex
 	client *redis.Client
+	mr     *miniredis.Miniredis
+	closed bool
 }
 
+// Client 返回客户端, 首次调用时创建; Close 之后返回已关闭的客户端, 不会重新创建
 func (r *Redis) Client() *redis.Client {
-	r.once.Do(func() {
+	r.mu.RLock()
+	client := r.client
+	r.mu.RUnlock()
+	if client != nil {
+		return client
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client == nil {
+		if r.closed {
+			panic(errors.New("redis 客户端已关闭"))
+		}
 		if r.Addr == "memory" || r.Addr == "" {
 			mr, err := miniredis.Run()
 			if err != nil {
 				panic(err)
 			}
+			r.mr = mr
 			r.client = redis.NewClient(&redis.Options{
 				Addr: mr.Addr(),
 			})
@@ -40,6 +56,24 @@ func (r *Redis) Client() *redis.Client {
 				DB:       r.DB,
 			})
 		}
-	})
+	}
 	return r.client
 }
+
+// Close 关闭 Redis 客户端, 未使用或已关闭时不做处理
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.client == nil {
+		return nil
+	}
+	err := r.client.Close()
+	if r.mr != nil {
+		r.mr.Close()
+	}
+	return err
+}
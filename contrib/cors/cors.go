@@ -16,9 +16,16 @@ import (
 	"github.com/veypi/vigo"
 )
 
-// IsCrossOrigin 完整的跨域判断
+// IsCrossOrigin 完整的跨域判断, 协议和 Host 只采用来自可信代理的 X-Forwarded-*/Forwarded 头
 func IsCrossOrigin(r *http.Request) bool {
-	origin := r.Header.Get("Origin")
+	return isCrossOrigin(r.Header.Get("Origin"), vigo.RequestScheme(r), vigo.RequestHost(r))
+}
+
+func isCrossOriginX(x *vigo.X) bool {
+	return isCrossOrigin(x.Request.Header.Get("Origin"), x.Scheme(), x.Host())
+}
+
+func isCrossOrigin(origin, scheme, host string) bool {
 	if origin == "" {
 		return false
 	}
@@ -28,19 +35,13 @@ func IsCrossOrigin(r *http.Request) bool {
 		return false
 	}
 
-	// 获取当前请求的协议
-	scheme := "http"
-	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
-		scheme = "https"
-	}
-
 	// 获取端口
-	requestPort := getPort(r.Host, scheme)
+	requestPort := getPort(host, scheme)
 	originPort := getPort(originURL.Host, originURL.Scheme)
 
 	// 比较协议、主机、端口
 	return scheme != originURL.Scheme ||
-		getHost(r.Host) != getHost(originURL.Host) ||
+		getHost(host) != getHost(originURL.Host) ||
 		requestPort != originPort
 }
 
@@ -65,7 +66,7 @@ func getPort(host, scheme string) string {
 }
 
 func AllowAny(x *vigo.X) {
-	if IsCrossOrigin(x.Request) {
+	if isCrossOriginX(x) {
 		origin := x.Request.Header.Get("Origin")
 		x.Header().Set("Access-Control-Allow-Origin", origin)
 		x.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -80,7 +81,7 @@ func AllowAny(x *vigo.X) {
 
 func CorsAllow(domains ...string) func(x *vigo.X) {
 	return func(x *vigo.X) {
-		if IsCrossOrigin(x.Request) {
+		if isCrossOriginX(x) {
 			origin := x.Request.Header.Get("Origin")
 			if slices.Contains(domains, origin) {
 				x.Header().Set("Access-Control-Allow-Origin", origin)
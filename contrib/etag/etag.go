@@ -0,0 +1,297 @@
+//
+// etag.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+// Package etag 为处理函数的响应生成 ETag/Last-Modified, 支持条件请求
+//
+//	router.Use(etag.Handler)                   // GET/HEAD 自动计算 ETag, 命中返回 304
+//	router.Put("/users/{id}", etag.IfMatch(loadUserVersion), updateUser) // 写操作校验 If-Match, 失败返回 412
+package etag
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash/fnv"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/veypi/vigo"
+)
+
+const (
+	// VersionKey 请求变量名, 处理函数通过 SetVersion 设置资源版本
+	VersionKey = "vigo.etag.version"
+	// ModifiedKey 请求变量名, 处理函数通过 SetModified 设置资源修改时间
+	ModifiedKey = "vigo.etag.modified"
+)
+
+// Versioned 返回值实现该接口时使用其版本号作为 ETag
+type Versioned interface {
+	Version() string
+}
+
+// Config ETag 中间件配置
+type Config struct {
+	// Weak 生成弱 ETag (W/"...")
+	Weak bool
+	// MaxSize 超过该大小的响应不再缓冲计算 ETag, 0 表示不限制
+	MaxSize int
+}
+
+// Handler 默认配置的 ETag 中间件
+var Handler = New(Config{})
+
+// New 创建 ETag 中间件, 仅处理 GET/HEAD 请求的 200 响应
+//
+// ETag 优先使用 SetVersion 或返回值的 Version() 方法, 否则对响应体做哈希;
+// Last-Modified 优先使用 SetModified, 否则取返回值 (或切片元素中最新) 的 UpdatedAt 字段.
+// 返回值指写出响应时的 x.PipeValue, 需配合 After 中的 common.JsonResponse/x.Render 等输出函数.
+// 流式响应 (调用了 Flush) 和超过 MaxSize 的响应直接透传
+func New(cfg Config) func(*vigo.X) {
+	return func(x *vigo.X) {
+		if x.Request.Method != http.MethodGet && x.Request.Method != http.MethodHead {
+			return
+		}
+		orig := x.ResponseWriter()
+		bw := &bufferWriter{ResponseWriter: orig, x: x, max: cfg.MaxSize}
+		x.SetResponseWriter(bw)
+		defer x.SetResponseWriter(orig)
+		x.Next()
+		if bw.passthrough {
+			return
+		}
+
+		status := bw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		h := orig.Header()
+		if status == http.StatusOK {
+			etag := h.Get("ETag")
+			if etag == "" {
+				if v, ok := x.Get(VersionKey).(string); ok && v != "" {
+					etag = Format(v, cfg.Weak)
+				} else if v, ok := bw.value.(Versioned); ok && v.Version() != "" {
+					etag = Format(v.Version(), cfg.Weak)
+				} else {
+					etag = Hash(bw.buf.Bytes(), cfg.Weak)
+				}
+				h.Set("ETag", etag)
+			}
+			modified, _ := x.Get(ModifiedKey).(time.Time)
+			if modified.IsZero() {
+				modified = updatedAt(bw.value)
+			}
+			if !modified.IsZero() && h.Get("Last-Modified") == "" {
+				h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+			}
+			if notModified(x.Request, etag, modified) {
+				h.Del("Content-Length")
+				orig.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		orig.WriteHeader(status)
+		orig.Write(bw.buf.Bytes())
+	}
+}
+
+// SetVersion 设置资源版本, 用于生成 ETag
+func SetVersion(x *vigo.X, version string) {
+	x.Set(VersionKey, version)
+}
+
+// SetModified 设置资源修改时间, 用于生成 Last-Modified
+func SetModified(x *vigo.X, t time.Time) {
+	x.Set(ModifiedKey, t)
+}
+
+// Format 将版本号格式化为 ETag
+func Format(version string, weak bool) string {
+	if strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`) {
+		return version
+	}
+	if weak {
+		return `W/"` + version + `"`
+	}
+	return `"` + version + `"`
+}
+
+// Hash 对内容做 FNV-1a 哈希生成 ETag
+func Hash(body []byte, weak bool) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return Format(hex.EncodeToString(h.Sum(nil)), weak)
+}
+
+// CheckIfMatch 校验写操作的 If-Match/If-Unmodified-Since 前置条件, 不满足时返回 vigo.ErrPreconditionFailed
+// current 为资源当前的 ETag 或版本号, modified 为资源当前修改时间, 可为零值
+func CheckIfMatch(x *vigo.X, current string, modified time.Time) error {
+	r := x.Request
+	if im := r.Header.Get("If-Match"); im != "" {
+		if current == "" || !matchETag(im, Format(current, false), true) {
+			return vigo.ErrPreconditionFailed.WithArgs("If-Match")
+		}
+		return nil
+	}
+	if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ius)
+		if err == nil && modified.Truncate(time.Second).After(t) {
+			return vigo.ErrPreconditionFailed.WithArgs("If-Unmodified-Since")
+		}
+	}
+	return nil
+}
+
+// IfMatch 创建写操作前置条件中间件, current 返回资源当前的版本号和修改时间
+func IfMatch(current func(x *vigo.X) (string, time.Time, error)) func(*vigo.X) error {
+	return func(x *vigo.X) error {
+		if x.Request.Header.Get("If-Match") == "" && x.Request.Header.Get("If-Unmodified-Since") == "" {
+			return nil
+		}
+		version, modified, err := current(x)
+		if err != nil {
+			return err
+		}
+		return CheckIfMatch(x, version, modified)
+	}
+}
+
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchETag(inm, etag, false)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// matchETag 比较 If-Match/If-None-Match 列表, strong 为 true 时弱 ETag 不匹配
+func matchETag(header, etag string, strong bool) bool {
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" {
+			return true
+		}
+		if strong && strings.HasPrefix(item, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// updatedAt 取结构体的 UpdatedAt 字段, 切片取最新的值
+func updatedAt(v any) time.Time {
+	if v == nil {
+		return time.Time{}
+	}
+	var latest time.Time
+	var walk func(rv reflect.Value)
+	walk = func(rv reflect.Value) {
+		for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return
+			}
+			rv = rv.Elem()
+		}
+		switch rv.Kind() {
+		case reflect.Struct:
+			f := rv.FieldByName("UpdatedAt")
+			if f.IsValid() && f.Type() == timeType {
+				if t := f.Interface().(time.Time); t.After(latest) {
+					latest = t
+				}
+			}
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				walk(rv.Index(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(v))
+	return latest
+}
+
+// bufferWriter 缓冲响应以计算 ETag, Flush 或超出大小后转为透传
+type bufferWriter struct {
+	http.ResponseWriter
+	x           *vigo.X
+	value       any // 首次写入时的 PipeValue, 即处理函数的返回值
+	captured    bool
+	buf         bytes.Buffer
+	status      int
+	max         int
+	passthrough bool
+}
+
+// capture 写出响应的中间件执行期间 PipeValue 仍是上一个处理函数的返回值
+func (w *bufferWriter) capture() {
+	if !w.captured {
+		w.captured = true
+		w.value = w.x.PipeValue
+	}
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	w.capture()
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	w.capture()
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.max > 0 && w.buf.Len()+len(p) > w.max {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *bufferWriter) Flush() {
+	if !w.passthrough {
+		w.startPassthrough()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bufferWriter) startPassthrough() error {
+	w.passthrough = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *bufferWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
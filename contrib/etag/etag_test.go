@@ -0,0 +1,123 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/veypi/vigo"
+)
+
+type item struct {
+	ID        int       `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type versioned struct{ V string }
+
+func (v versioned) Version() string { return v.V }
+
+func TestHandler(t *testing.T) {
+	t1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	r := vigo.NewRouter()
+	r.Use(Handler)
+	r.After(func(x *vigo.X, data any) error { return x.JSON(data) })
+	r.Get("/list", func(x *vigo.X) ([]item, error) {
+		return []item{{1, t1}, {2, t2}}, nil
+	})
+	r.Get("/ver", func(x *vigo.X) (any, error) { return versioned{"v7"}, nil })
+	r.Get("/set", func(x *vigo.X) (any, error) {
+		SetVersion(x, "abc")
+		SetModified(x, t1)
+		return "ok", nil
+	})
+	r.Get("/stream", func(x *vigo.X) {
+		x.Write([]byte("a"))
+		x.Flush()
+	})
+
+	do := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/list")
+	etag := w.Header().Get("ETag")
+	if w.Code != 200 || etag == "" || w.Body.Len() == 0 {
+		t.Fatalf("unexpected response %d %q %q", w.Code, etag, w.Body.String())
+	}
+	if lm := w.Header().Get("Last-Modified"); lm != t2.Format(http.TimeFormat) {
+		t.Errorf("expected latest UpdatedAt, got %q", lm)
+	}
+	if w := do("/list", "If-None-Match", `"x", `+etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304, got %d %q", w.Code, w.Body.String())
+	}
+	if w := do("/list", "If-None-Match", `W/`+etag); w.Code != http.StatusNotModified {
+		t.Errorf("expected weak comparison 304, got %d", w.Code)
+	}
+	if w := do("/list", "If-Modified-Since", t2.Format(http.TimeFormat)); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for If-Modified-Since, got %d", w.Code)
+	}
+	if w := do("/list", "If-Modified-Since", t1.Format(http.TimeFormat)); w.Code != 200 {
+		t.Errorf("expected 200 for older If-Modified-Since, got %d", w.Code)
+	}
+	if w := do("/ver"); w.Header().Get("ETag") != `"v7"` {
+		t.Errorf("expected version ETag, got %q", w.Header().Get("ETag"))
+	}
+	w = do("/set", "If-None-Match", `"abc"`)
+	if w.Code != http.StatusNotModified || w.Header().Get("Last-Modified") != t1.Format(http.TimeFormat) {
+		t.Errorf("expected 304 with handler version, got %d %v", w.Code, w.Header())
+	}
+	if w := do("/stream"); w.Header().Get("ETag") != "" || w.Body.String() != "a" || !w.Flushed {
+		t.Errorf("expected streamed response to pass through, got %v %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	updated := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := vigo.NewRouter()
+	r.Put("/item", IfMatch(func(x *vigo.X) (string, time.Time, error) {
+		return "v2", updated, nil
+	}), func(x *vigo.X) error {
+		x.WriteHeader(204)
+		return nil
+	}, func(x *vigo.X, err error) error {
+		if e, ok := err.(*vigo.Error); ok && e.Code == 41200 {
+			x.WriteHeader(412)
+			return nil
+		}
+		return err
+	})
+
+	tests := []struct {
+		header, value string
+		code          int
+	}{
+		{"", "", 204},
+		{"If-Match", `"v2"`, 204},
+		{"If-Match", `"v1", "v2"`, 204},
+		{"If-Match", `*`, 204},
+		{"If-Match", `"v1"`, 412},
+		{"If-Match", `W/"v2"`, 412},
+		{"If-Unmodified-Since", updated.Format(http.TimeFormat), 204},
+		{"If-Unmodified-Since", updated.Add(-time.Hour).Format(http.TimeFormat), 412},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/item", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: %s expected %d, got %d", tt.header, tt.value, tt.code, w.Code)
+		}
+	}
+}
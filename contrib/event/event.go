@@ -319,6 +319,13 @@ func (e *EventManager) Stop() {
 	e.wg.Wait()
 }
 
+// Running reports whether the EventManager has been started and not stopped.
+func (e *EventManager) Running() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.running
+}
+
 // Cancel stops a specific task by its key and removes it from the registry.
 // If the task is running, its context will be cancelled.
 // If the task is not found, this is a no-op.
@@ -444,7 +451,7 @@ func (e *EventManager) executeTask(ctx context.Context, item *taskItem, source s
 				logv.Error().Str("id", item.key).Str("source", source).Dur("duration", cost).Msg(fmt.Sprintf("Task panic recovered: %v", r))
 			} else {
 				if err != nil {
-					logv.WithNoCaller.Error().Err(err).Str("id", item.key).Str("source", source).Dur("duration", cost).Msg("vigo.event")
+					logv.WithNoCaller.Error().Err(logv.Detailed(err)).Str("id", item.key).Str("source", source).Dur("duration", cost).Msg("vigo.event")
 				} else {
 					logv.WithNoCaller.Debug().Str("id", item.key).Str("source", source).Dur("duration", cost).Msg("vigo.event")
 				}
@@ -622,6 +629,11 @@ func Stop() {
 	Default.Stop()
 }
 
+// Running reports whether the default event manager is running.
+func Running() bool {
+	return Default.Running()
+}
+
 // Cancel cancels a task in the default event manager.
 func Cancel(key string) {
 	Default.Cancel(key)
@@ -0,0 +1,301 @@
+//
+// health.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+// Package health 提供 /healthz, /readyz, /livez 健康检查
+//
+//	h := health.New()
+//	h.Add("db", health.Database(&cfg.DB), health.Timeout(2*time.Second))
+//	h.Add("redis", health.Redis(&cfg.Redis))
+//	h.Add("event", health.Event(nil), health.Liveness())
+//	h.Register(router)
+//	app.BeforeShutdown(h.Shutdown) // 开始关闭后 /readyz 返回 503
+//
+// 默认只返回 {"status":"ok"}, 请求带 ?verbose 时返回各检查项的结果和耗时
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/veypi/vigo"
+	"github.com/veypi/vigo/contrib/config"
+	"github.com/veypi/vigo/contrib/event"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+// CheckFunc 检查函数, 返回 nil 表示健康
+type CheckFunc func(ctx context.Context) error
+
+type kind int
+
+const (
+	readiness kind = 1 << iota
+	liveness
+)
+
+type check struct {
+	name    string
+	fn      CheckFunc
+	timeout time.Duration
+	ttl     time.Duration
+	kind    kind
+
+	mu     sync.Mutex
+	result Result
+}
+
+// Option 检查项配置
+type Option func(*check)
+
+// Timeout 单个检查的超时, 超时视为失败
+func Timeout(d time.Duration) Option {
+	return func(c *check) {
+		c.timeout = d
+	}
+}
+
+// Cache 检查结果的缓存时间, 避免频繁探测打满数据库, 0 表示不缓存
+func Cache(d time.Duration) Option {
+	return func(c *check) {
+		c.ttl = d
+	}
+}
+
+// Liveness 检查项同时用于 /livez, 失败时进程会被重启, 只应用于进程自身的状态
+func Liveness() Option {
+	return func(c *check) {
+		c.kind |= liveness
+	}
+}
+
+// Result 单个检查项的结果
+type Result struct {
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"-"`
+	Took      string        `json:"duration"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Cached    bool          `json:"cached,omitempty"`
+}
+
+// Report 检查结果
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Health 健康检查集合
+type Health struct {
+	// Timeout 未单独设置时每个检查的超时, 默认 1s
+	Timeout time.Duration
+	// CacheTTL 未单独设置时检查结果的缓存时间, 默认 1s
+	CacheTTL time.Duration
+
+	mu           sync.RWMutex
+	checks       []*check
+	shuttingDown atomic.Bool
+}
+
+func New() *Health {
+	return &Health{Timeout: time.Second, CacheTTL: time.Second}
+}
+
+// Add 按名称注册检查项, 默认用于 /readyz 和 /healthz; 同名检查项会被替换
+func (h *Health) Add(name string, fn CheckFunc, opts ...Option) *Health {
+	c := &check{name: name, fn: fn, timeout: h.Timeout, ttl: h.CacheTTL, kind: readiness}
+	for _, opt := range opts {
+		opt(c)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, old := range h.checks {
+		if old.name == name {
+			h.checks[i] = c
+			return h
+		}
+	}
+	h.checks = append(h.checks, c)
+	return h
+}
+
+// Shutdown 标记开始关闭, 之后 /readyz 返回 503
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// ShuttingDown 是否已开始关闭
+func (h *Health) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Register 在 router 上注册 /healthz, /readyz, /livez
+func (h *Health) Register(r vigo.Router) {
+	r.Get("/healthz", "health check", h.handler(readiness|liveness))
+	r.Get("/readyz", "readiness check", h.handler(readiness))
+	r.Get("/livez", "liveness check", h.handler(liveness))
+}
+
+// Healthz 执行全部检查
+func (h *Health) Healthz(ctx context.Context) Report {
+	return h.run(ctx, readiness|liveness)
+}
+
+// Readyz 执行 readiness 检查, 开始关闭后直接返回 shutting_down
+func (h *Health) Readyz(ctx context.Context) Report {
+	return h.run(ctx, readiness)
+}
+
+// Livez 执行 liveness 检查
+func (h *Health) Livez(ctx context.Context) Report {
+	return h.run(ctx, liveness)
+}
+
+func (h *Health) run(ctx context.Context, k kind) Report {
+	report := Report{Status: StatusOK, Checks: map[string]Result{}}
+	if k == readiness && h.ShuttingDown() {
+		report.Status = StatusShuttingDown
+		return report
+	}
+	h.mu.RLock()
+	checks := make([]*check, 0, len(h.checks))
+	for _, c := range h.checks {
+		if c.kind&k != 0 {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl > 0 && !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < c.ttl {
+		res := c.result
+		res.Cached = true
+		return res
+	}
+	start := time.Now()
+	err := c.call(ctx)
+	res := Result{Status: StatusOK, Duration: time.Since(start), CheckedAt: start}
+	res.Took = res.Duration.String()
+	if err != nil {
+		res.Status, res.Error = StatusFail, err.Error()
+	}
+	c.result = res
+	return res
+}
+
+// call 在超时内执行检查, 不响应 ctx 的检查函数也不会阻塞探测
+func (c *check) call(ctx context.Context) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				done <- fmt.Errorf("panic: %v", e)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timeout after %s", c.timeout)
+		}
+		return ctx.Err()
+	}
+}
+
+func (h *Health) handler(k kind) func(*vigo.X) error {
+	return func(x *vigo.X) error {
+		report := h.run(x.Request.Context(), k)
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		body := any(map[string]string{"status": report.Status})
+		if x.Request.URL.Query().Has("verbose") {
+			body = report
+		}
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		x.Header().Set("Content-Type", "application/json")
+		x.Header().Set("Cache-Control", "no-store")
+		x.WriteHeader(status)
+		_, err = x.Write(b)
+		return err
+	}
+}
+
+// Database 检查数据库连接
+func Database(d *config.Database) CheckFunc {
+	return func(ctx context.Context) error {
+		db, err := d.Client().DB()
+		if err != nil {
+			return err
+		}
+		return db.PingContext(ctx)
+	}
+}
+
+// Redis 检查 Redis 连接
+func Redis(r *config.Redis) CheckFunc {
+	return func(ctx context.Context) error {
+		return r.Client().Ping(ctx).Err()
+	}
+}
+
+// Event 检查后台任务管理器是否在运行, m 为 nil 时检查 event.Default
+func Event(m *event.EventManager) CheckFunc {
+	return func(ctx context.Context) error {
+		mgr := m
+		if mgr == nil {
+			mgr = event.Default
+		}
+		if !mgr.Running() {
+			return errors.New("event manager is not running")
+		}
+		return nil
+	}
+}
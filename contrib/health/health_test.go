@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/veypi/vigo"
+	"github.com/veypi/vigo/contrib/config"
+	"github.com/veypi/vigo/contrib/event"
+)
+
+func do(r vigo.Router, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestHealth(t *testing.T) {
+	var dbCalls atomic.Int32
+	dbErr := atomic.Pointer[error]{}
+	h := New()
+	h.Add("db", func(ctx context.Context) error {
+		dbCalls.Add(1)
+		if e := dbErr.Load(); e != nil {
+			return *e
+		}
+		return nil
+	}, Cache(time.Hour))
+	h.Add("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}, Timeout(20*time.Millisecond), Cache(0))
+	h.Add("goroutines", func(ctx context.Context) error { return nil }, Liveness())
+	r := vigo.NewRouter()
+	h.Register(r)
+
+	w := do(r, "/readyz")
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != `{"status":"fail"}` {
+		t.Errorf("slow check should time out: %d %s", w.Code, w.Body.String())
+	}
+	w = do(r, "/readyz?verbose")
+	for _, want := range []string{`"db":{"status":"ok"`, `"cached":true`, `"error":"timeout after 20ms"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("verbose output missing %s: %s", want, w.Body.String())
+		}
+	}
+	if !strings.Contains(w.Body.String(), "goroutines") {
+		t.Error("liveness checks also count for readiness")
+	}
+	if dbCalls.Load() != 1 {
+		t.Errorf("cached check should run once, ran %d", dbCalls.Load())
+	}
+
+	h.Add("slow", func(ctx context.Context) error { return nil })
+	if w := do(r, "/healthz?verbose"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutines") {
+		t.Errorf("unexpected healthz %d %s", w.Code, w.Body.String())
+	}
+	if w := do(r, "/livez?verbose"); w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"db"`) {
+		t.Errorf("livez should only run liveness checks: %s", w.Body.String())
+	}
+
+	h.Shutdown()
+	if w := do(r, "/readyz"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), StatusShuttingDown) {
+		t.Errorf("readyz should fail during shutdown: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(r, "/livez"); w.Code != http.StatusOK {
+		t.Error("livez should stay healthy during shutdown")
+	}
+}
+
+func TestCheckPanic(t *testing.T) {
+	h := New()
+	h.Add("panic", func(ctx context.Context) error { panic("boom") })
+	if rep := h.Readyz(context.Background()); rep.Checks["panic"].Error != "panic: boom" {
+		t.Errorf("unexpected result %+v", rep)
+	}
+}
+
+func TestBuiltinChecks(t *testing.T) {
+	db := &config.Database{Type: "sqlite", DSN: ":memory:"}
+	defer db.Close()
+	rdb := &config.Redis{Addr: "memory"}
+	defer rdb.Close()
+	m := event.NewEventManager()
+
+	h := New()
+	h.Add("db", Database(db)).Add("redis", Redis(rdb)).Add("event", Event(m), Cache(0))
+	rep := h.Readyz(context.Background())
+	if rep.Checks["db"].Status != StatusOK || rep.Checks["redis"].Status != StatusOK {
+		t.Errorf("unexpected report %+v", rep)
+	}
+	if rep.Status != StatusFail || rep.Checks["event"].Error == "" {
+		t.Errorf("stopped event manager should fail: %+v", rep)
+	}
+	m.Start()
+	defer m.Stop()
+	if rep := h.Readyz(context.Background()); rep.Status != StatusOK {
+		t.Errorf("expected ok, got %+v", rep)
+	}
+	// Client() 连接失败时 panic, 记为检查失败
+	h.Add("bad", Database(&config.Database{Type: "unknown"}))
+	if rep := h.Readyz(context.Background()); !strings.HasPrefix(rep.Checks["bad"].Error, "panic:") {
+		t.Errorf("expected panic error, got %+v", rep.Checks["bad"])
+	}
+	// 关闭后不会悄悄重新连接
+	db.Close()
+	rdb.Close()
+	rep = New().Add("db", Database(db)).Add("redis", Redis(rdb)).Readyz(context.Background())
+	if rep.Checks["db"].Status == StatusOK || rep.Checks["redis"].Status == StatusOK {
+		t.Errorf("closed clients should fail: %+v", rep)
+	}
+}
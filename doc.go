@@ -51,6 +51,7 @@ type DocRoute struct {
 	Body     *DocBody           `json:"body,omitempty" yaml:"body,omitempty"`
 	Actions  []*DocAction       `json:"actions,omitempty" yaml:"actions,omitempty"`
 	Response *DocBody           `json:"response,omitempty" yaml:"response,omitempty"`
+	Errors   []*ErrorInfo       `json:"errors,omitempty" yaml:"errors,omitempty"`
 	Others   map[uint]*DocRoute `json:"others,omitempty" yaml:"others,omitempty"`
 }
 
@@ -68,13 +69,15 @@ type DocParam struct {
 	Required bool        `json:"required" yaml:"required"`
 	Desc     string      `json:"desc,omitempty" yaml:"desc,omitempty"`
 	Default  interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	Style    string      `json:"style,omitempty" yaml:"style,omitempty"` // query array/object style: form, comma, pipe, space, deepObject
 }
 
 type DocBody struct {
-	ContentType string      `json:"content_type" yaml:"content_type"`         // application/json, multipart/form-data
-	Type        string      `json:"type" yaml:"type"`                         // string, int, bool, number, object, array
-	Item        *DocField   `json:"item,omitempty" yaml:"item,omitempty"`     // For arrays
-	Fields      []*DocField `json:"fields,omitempty" yaml:"fields,omitempty"` // For objects
+	ContentType string      `json:"content_type" yaml:"content_type"`           // application/json, multipart/form-data
+	Type        string      `json:"type" yaml:"type"`                           // string, int, bool, number, object, array, binary
+	Item        *DocField   `json:"item,omitempty" yaml:"item,omitempty"`       // For arrays
+	Fields      []*DocField `json:"fields,omitempty" yaml:"fields,omitempty"`   // For objects
+	Formats     []string    `json:"formats,omitempty" yaml:"formats,omitempty"` // x.Render 可协商的响应格式
 }
 
 type DocField struct {
@@ -135,12 +138,17 @@ func (app *Application) EnableApiDoc() {
 					r.Params = nil
 					r.Body = nil
 					r.Response = nil
+					r.Errors = nil
 				}
 			}
 
 			x.Stop()
 			return x.JSON(res)
 		})
+		_ = app.Router().Get(app.config.DocPath+"/errors.json", "get error code catalog", func(x *X) error {
+			x.Stop()
+			return x.JSON(Errors())
+		})
 		_ = app.Router().Get(app.config.DocPath, func(x *X) error {
 			x.Stop()
 			jsonPath := "./" + filepath.Base(app.config.DocPath) + ".json"
@@ -179,6 +187,7 @@ func (r *route) Doc() *Doc {
 					Method:  method,
 					Path:    currentPath,
 					Summary: mh.Desc,
+					Errors:  docErrors(mh.Errors),
 				}
 
 				if handlersInfo, ok := node.handlersInfoCache[method]; ok {
@@ -237,10 +246,18 @@ func (r *route) Doc() *Doc {
 
 				// Parse Response (Only 200 OK)
 				if mh.Response != nil {
-					if t, ok := mh.Response.(reflect.Type); ok {
-						route.Response = parseDocResponse(t)
-					} else {
-						route.Response = parseDocResponse(reflect.TypeOf(mh.Response))
+					t, ok := mh.Response.(reflect.Type)
+					if !ok {
+						t = reflect.TypeOf(mh.Response)
+					}
+					// 统一响应包装时描述包装后的结构
+					if env, ok := node.varsCache[EnvelopeKey].(Envelope); ok {
+						t = env.Schema(t)
+					}
+					route.Response = parseDocResponse(t)
+					// 只为设置了 RenderFormatsKey 的路由列出 x.Render 可协商的格式
+					if allowed, ok := node.varsCache[RenderFormatsKey].([]string); ok && route.Response != nil {
+						route.Response.Formats = RenderFormats(derefType(t), allowed)
 					}
 				}
 
@@ -318,23 +335,43 @@ func parseDocArgs(t reflect.Type) ([]*DocParam, *DocBody) {
 					required = false
 				}
 
+				paramType := getDocType(field.Type)
+				if isTextType(field.Type) {
+					paramType = "string"
+				}
 				p := &DocParam{
 					Name:     name,
 					In:       source,
-					Type:     getDocType(field.Type),
+					Type:     paramType,
 					Required: required,
 					Desc:     desc,
 				}
 				if defaultVal != "" {
 					p.Default = defaultVal
 				}
+				if source == "query" {
+					p.Style = getFieldStyle(field)
+				}
 
 				if source == "path" && len(parts) > 1 {
 					p.Name = parts[1] // Use alias for path param
 				}
 				params = append(params, p)
 
+			case "body":
+				// 流式请求体, 按二进制描述
+				body = &DocBody{
+					ContentType: field.Tag.Get("mime"),
+					Type:        "binary",
+				}
+				if body.ContentType == "" {
+					body.ContentType = "application/octet-stream"
+				}
+
 			case "json", "form":
+				if body != nil && body.Type == "binary" {
+					continue
+				}
 				if body == nil {
 					body = &DocBody{
 						Type:   "object",
@@ -364,6 +401,22 @@ func parseDocArgs(t reflect.Type) ([]*DocParam, *DocBody) {
 	return params, body
 }
 
+// docErrors 声明的错误, 描述取自错误码表
+func docErrors(errs []*Error) []*ErrorInfo {
+	if len(errs) == 0 {
+		return nil
+	}
+	res := make([]*ErrorInfo, 0, len(errs))
+	for _, e := range errs {
+		info := &ErrorInfo{Code: e.Code, Status: e.Status(), Message: e.Message}
+		if reg, ok := LookupError(e.Code); ok {
+			info.Desc = reg.Desc
+		}
+		res = append(res, info)
+	}
+	return res
+}
+
 func parseDocResponse(t reflect.Type) *DocBody {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
@@ -291,3 +291,19 @@ func TestDocIntegration(t *testing.T) {
 		t.Errorf("Route /version response type expected string, got %s", routeVersion.Response.Type)
 	}
 }
+
+func TestParseDocArgs_QueryStyle(t *testing.T) {
+	type StyleReq struct {
+		IDs    []int             `src:"query"`
+		Names  []string          `src:"query" style:"comma"`
+		Filter map[string]string `src:"query"`
+		Page   int               `src:"query"`
+	}
+	params, _ := parseDocArgs(reflect.TypeOf(StyleReq{}))
+	expected := map[string]string{"IDs": "form", "Names": "comma", "Filter": "deepObject", "Page": ""}
+	for _, p := range params {
+		if p.Style != expected[p.Name] {
+			t.Errorf("%s: expected style %q, got %q", p.Name, expected[p.Name], p.Style)
+		}
+	}
+}
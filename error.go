@@ -8,98 +8,258 @@
 package vigo
 
 import (
-	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 )
 
 var (
 	// 4xx 客户端错误
 	// 400xx 参数相关错误
-	ErrBadRequest = NewError("bad request").WithCode(40000)
-	ErrInvalidArg = NewError("invalid arg").WithCode(40001)
-	ErrMissingArg = NewError("missing arg").WithCode(40002)
-	ErrArgFormat  = NewError("arg format error").WithCode(40003)
+	ErrBadRequest = Register(40000, "bad request")
+	ErrInvalidArg = Register(40001, "invalid arg")
+	ErrMissingArg = Register(40002, "missing arg")
+	ErrArgFormat  = Register(40003, "arg format error")
 
 	// 401xx 认证授权相关错误
-	ErrUnauthorized = NewError("unauthorized").WithCode(40100)  // 未登录/无token
-	ErrTokenInvalid = NewError("token invalid").WithCode(40101) // token无效
-	ErrTokenExpired = NewError("token expired").WithCode(40102) // token过期
-	ErrNoPermission = NewError("no permission").WithCode(40103) // 无操作权限
-	ErrForbidden    = NewError("forbidden").WithCode(40300)     // 禁止访问
+	ErrUnauthorized = Register(40100, "unauthorized", "未登录/无token")
+	ErrTokenInvalid = Register(40101, "token invalid", "token无效")
+	ErrTokenExpired = Register(40102, "token expired", "token过期")
+	ErrNoPermission = Register(40103, "no permission", "无操作权限")
+	ErrForbidden    = Register(40300, "forbidden", "禁止访问")
 
 	// 404xx 资源不存在
-	ErrNotFound         = NewError("not found").WithCode(40400)
-	ErrResourceNotFound = NewError("resource not found").WithCode(40401)
-	ErrEndpointNotFound = NewError("endpoint not found").WithCode(40402)
+	ErrNotFound         = Register(40400, "not found")
+	ErrResourceNotFound = Register(40401, "resource not found")
+	ErrEndpointNotFound = Register(40402, "endpoint not found")
+
+	// 406xx 无法协商响应格式
+	ErrNotAcceptable = Register(40600, "not acceptable")
 
 	// 409xx 资源冲突
-	ErrConflict      = NewError("resource conflict").WithCode(40900)
-	ErrAlreadyExists = NewError("resource already exists").WithCode(40901)
+	ErrConflict      = Register(40900, "resource conflict")
+	ErrAlreadyExists = Register(40901, "resource already exists")
+
+	// 412xx 前置条件失败
+	ErrPreconditionFailed = Register(41200, "precondition failed")
+
+	// 413xx 请求体过大
+	ErrPayloadTooLarge = Register(41300, "payload too large")
 
 	// 429xx 限流
-	ErrTooManyRequests = NewError("too many requests").WithCode(42900)
+	ErrTooManyRequests = Register(42900, "too many requests")
 
 	// 5xx 服务端错误
 	// 500xx 内部错误
-	ErrInternalServer = NewError("internal server error").WithCode(50000)
-	ErrDatabase       = NewError("database error").WithCode(50001)
-	ErrCache          = NewError("cache error").WithCode(50002)
-	ErrThirdParty     = NewError("third party service error").WithCode(50003)
+	ErrInternalServer = Register(50000, "internal server error")
+	ErrDatabase       = Register(50001, "database error")
+	ErrCache          = Register(50002, "cache error")
+	ErrThirdParty     = Register(50003, "third party service error")
 
 	// 501xx 功能相关
-	ErrNotImplemented = NewError("not implemented").WithCode(50100)
-	ErrNotSupported   = NewError("not supported").WithCode(50101)
+	ErrNotImplemented = Register(50100, "not implemented")
+	ErrNotSupported   = Register(50101, "not supported")
 
 	// 503xx 服务不可用
-	ErrServiceUnavailable = NewError("service unavailable").WithCode(50300)
+	ErrServiceUnavailable = Register(50300, "service unavailable")
 )
 
+// ErrorStack 开启后 NewError 及 With* 派生的错误会记录调用栈
+var ErrorStack = false
+
+// Error 业务错误
+//
+// Message 为返回给客户端的公开信息, Internal 和 cause 仅通过 %+v 输出到日志.
+// 已登记的错误码按 Code 匹配, 因此派生出的错误仍能匹配 ErrNotFound 等预定义错误;
+// 未登记的错误 (如 NewError) 只匹配由同一错误派生的错误
 type Error struct {
 	Code    int
 	Message string
+	// Internal 内部信息, 不返回给客户端
+	Internal string `json:"-"`
+	// Details 结构化的附加信息
+	Details map[string]any `json:"details,omitempty"`
+	// Fields 字段级错误, 字段名 -> 错误信息
+	Fields map[string]string `json:"fields,omitempty"`
+
+	cause error
+	stack []uintptr
+	// origin 派生链的源头, 用于未登记错误码的匹配
+	origin *Error
 }
 
 var _ error = &Error{}
 
+// Error 只包含错误码和公开信息, 可以直接返回给客户端
 func (e *Error) Error() string {
 	return fmt.Sprintf("code: %d, message: %s", e.Code, e.Message)
 }
 
+// Format %+v 额外输出内部信息和原始错误, 用于日志
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, e.Error())
+		if e.Internal != "" {
+			io.WriteString(s, ", internal: "+e.Internal)
+		}
+		if e.cause != nil {
+			fmt.Fprintf(s, ", cause: %+v", e.cause)
+		}
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+// Unwrap 返回原始错误
 func (e *Error) Unwrap() error {
-	return errors.New(e.Message)
+	return e.cause
+}
+
+// Is 已登记的错误码相同即视为同一错误, 未登记的错误比较派生源头
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t == nil || t.Code != e.Code {
+		return false
+	}
+	if _, registered := LookupError(t.Code); registered {
+		return true
+	}
+	return e.root() == t.root()
+}
+
+func (e *Error) root() *Error {
+	if e.origin != nil {
+		return e.origin
+	}
+	return e
+}
+
+// Status 返回错误码对应的 HTTP 状态码, 5 位错误码取前 3 位
+func (e *Error) Status() int {
+	code := e.Code
+	for code > 999 {
+		code /= 10
+	}
+	if code < 100 {
+		return 400
+	}
+	return code
+}
+
+// Stack 返回记录的调用栈, 未开启 ErrorStack 或 WithStack 时为空
+func (e *Error) Stack() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(e.stack)
+	res := make([]runtime.Frame, 0, len(e.stack))
+	for {
+		f, more := frames.Next()
+		res = append(res, f)
+		if !more {
+			break
+		}
+	}
+	return res
+}
+
+// StackString 返回格式化的调用栈
+func (e *Error) StackString() string {
+	var b strings.Builder
+	for _, f := range e.Stack() {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+	}
+	return b.String()
+}
+
+// derive 复制错误, 保留原因和附加信息
+func (e *Error) derive(msg string) *Error {
+	n := &Error{
+		Code:     e.Code,
+		Message:  msg,
+		Internal: e.Internal,
+		Details:  maps.Clone(e.Details),
+		Fields:   maps.Clone(e.Fields),
+		cause:    e.cause,
+		stack:    e.stack,
+		origin:   e.root(),
+	}
+	if ErrorStack {
+		n.stack = callers(4)
+	}
+	return n
 }
 
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
+}
+
+// WithCode 修改错误码, 不会登记到错误码表, 预设错误请使用 Register
 func (e *Error) WithCode(code int) *Error {
 	e.Code = code
 	return e
 }
 
 func (e *Error) WithArgs(a ...any) *Error {
-	return &Error{
-		Code:    e.Code,
-		Message: e.Message + ": " + fmt.Sprint(a...),
-	}
+	return e.derive(e.Message + ": " + fmt.Sprint(a...))
 }
 
 func (e *Error) WithString(a string) *Error {
-	return &Error{
-		Code:    e.Code,
-		Message: e.Message + ": " + a,
-	}
+	return e.derive(e.Message + ": " + a)
 }
 
 func (e *Error) WithMessage(msg string) *Error {
-	return &Error{
-		Code:    e.Code,
-		Message: msg,
-	}
+	return e.derive(msg)
 }
 
+// WithError 记录原始错误, 可通过 errors.Is/As 访问, 不会返回给客户端
 func (e *Error) WithError(err error) *Error {
-	return &Error{
-		Code:    e.Code,
-		Message: e.Message + "\n" + err.Error(),
+	n := e.derive(e.Message)
+	n.cause = err
+	return n
+}
+
+// WithInternal 设置内部信息, 不会返回给客户端
+func (e *Error) WithInternal(format string, a ...any) *Error {
+	n := e.derive(e.Message)
+	n.Internal = fmt.Sprintf(format, a...)
+	return n
+}
+
+// WithDetail 添加附加信息
+func (e *Error) WithDetail(key string, value any) *Error {
+	n := e.derive(e.Message)
+	if n.Details == nil {
+		n.Details = make(map[string]any)
+	}
+	n.Details[key] = value
+	return n
+}
+
+// WithField 添加字段级错误
+func (e *Error) WithField(field, msg string) *Error {
+	n := e.derive(e.Message)
+	if n.Fields == nil {
+		n.Fields = make(map[string]string)
 	}
+	n.Fields[field] = msg
+	return n
+}
+
+// WithStack 记录当前调用栈
+func (e *Error) WithStack() *Error {
+	n := e.derive(e.Message)
+	n.stack = callers(3)
+	return n
 }
 
 func NewError(msg string, a ...any) *Error {
@@ -110,5 +270,55 @@ func NewError(msg string, a ...any) *Error {
 	if len(a) > 0 {
 		e.Message = fmt.Sprintf(msg, a...)
 	}
+	if ErrorStack {
+		e.stack = callers(3)
+	}
 	return e
 }
+
+// ErrorInfo 错误码表中的一项
+type ErrorInfo struct {
+	Code    int    `json:"code" yaml:"code"`
+	Status  int    `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+	Desc    string `json:"desc,omitempty" yaml:"desc,omitempty"`
+}
+
+var (
+	errorRegistryMu sync.RWMutex
+	errorRegistry   = map[int]*ErrorInfo{}
+)
+
+// Register 定义预设错误并登记到错误码表, 错误码重复时 panic
+//
+//	var ErrUserBanned = vigo.Register(40310, "user banned", "账号已被封禁")
+func Register(code int, msg string, desc ...string) *Error {
+	errorRegistryMu.Lock()
+	defer errorRegistryMu.Unlock()
+	if old, ok := errorRegistry[code]; ok {
+		panic(fmt.Sprintf("vigo: duplicate error code %d: %q and %q", code, old.Message, msg))
+	}
+	e := &Error{Code: code, Message: msg}
+	errorRegistry[code] = &ErrorInfo{Code: code, Status: e.Status(), Message: msg, Desc: strings.Join(desc, " ")}
+	return e
+}
+
+// LookupError 按错误码查找已登记的错误
+func LookupError(code int) (*ErrorInfo, bool) {
+	errorRegistryMu.RLock()
+	defer errorRegistryMu.RUnlock()
+	info, ok := errorRegistry[code]
+	return info, ok
+}
+
+// Errors 返回按错误码排序的错误码表
+func Errors() []*ErrorInfo {
+	errorRegistryMu.RLock()
+	defer errorRegistryMu.RUnlock()
+	res := make([]*ErrorInfo, 0, len(errorRegistry))
+	for _, info := range errorRegistry {
+		res = append(res, info)
+	}
+	slices.SortFunc(res, func(a, b *ErrorInfo) int { return a.Code - b.Code })
+	return res
+}
@@ -0,0 +1,166 @@
+package vigo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/veypi/vigo/logv"
+)
+
+func TestErrorIs(t *testing.T) {
+	err := ErrNotFound.WithArgs("user 1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("derived error should match sentinel by code")
+	}
+	if errors.Is(err, ErrResourceNotFound) {
+		t.Error("different code should not match")
+	}
+	wrapped := fmt.Errorf("load: %w", err)
+	if !errors.Is(wrapped, ErrNotFound) {
+		t.Error("wrapped error should match sentinel")
+	}
+	var e *Error
+	if !errors.As(wrapped, &e) || e.Message != "not found: user 1" {
+		t.Errorf("errors.As failed: %v", e)
+	}
+
+	// 未登记的错误码只匹配同一来源
+	errA, errB := NewError("a"), NewError("b")
+	if errors.Is(errA, errB) {
+		t.Error("unrelated ad-hoc errors should not match")
+	}
+	if !errors.Is(errA.WithArgs("x").WithField("f", "y"), errA) || !errors.Is(errA, errA) {
+		t.Error("derived ad-hoc error should match its origin")
+	}
+	if errors.Is(errA.WithArgs("x"), errB) {
+		t.Error("derived ad-hoc error should not match other errors")
+	}
+}
+
+func TestErrorCause(t *testing.T) {
+	err := ErrDatabase.WithError(fs.ErrNotExist)
+	if !errors.Is(err, fs.ErrNotExist) || !errors.Is(err, ErrDatabase) {
+		t.Error("cause should be reachable by errors.Is")
+	}
+	if err.Message != "database error" {
+		t.Errorf("cause should not leak into public message: %q", err.Message)
+	}
+	if strings.Contains(err.Error(), fs.ErrNotExist.Error()) {
+		t.Errorf("Error() should not include cause: %q", err.Error())
+	}
+	if s := fmt.Sprintf("%+v", err); !strings.Contains(s, fs.ErrNotExist.Error()) {
+		t.Errorf("%%+v should include cause: %q", s)
+	}
+	// 派生错误保留原因
+	if !errors.Is(err.WithArgs("x"), fs.ErrNotExist) {
+		t.Error("derived error should keep cause")
+	}
+	if ErrNotFound.Unwrap() != nil {
+		t.Error("sentinel should have no cause")
+	}
+}
+
+func TestErrorDetails(t *testing.T) {
+	base := ErrInvalidArg.WithField("name", "required")
+	err := base.WithField("age", "too small").WithDetail("limit", 18).WithInternal("user %d", 7)
+	if len(base.Fields) != 1 || len(err.Fields) != 2 || err.Details["limit"] != 18 {
+		t.Errorf("unexpected fields %v %v %v", base.Fields, err.Fields, err.Details)
+	}
+	if err.Message != "invalid arg" || err.Internal != "user 7" || strings.Contains(err.Error(), "user 7") {
+		t.Errorf("unexpected messages %q %q", err.Message, err.Error())
+	}
+	if s := fmt.Sprintf("%+v", fmt.Errorf("wrap: %w", err)); strings.Contains(s, "user 7") {
+		t.Errorf("fmt.Errorf should only use Error(): %q", s)
+	}
+	if s := fmt.Sprintf("%+v", err); !strings.Contains(s, "internal: user 7") {
+		t.Errorf("%%+v should include internal: %q", s)
+	}
+	if ErrInvalidArg.Fields != nil {
+		t.Error("sentinel should not be modified")
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	if len(ErrNotFound.WithArgs("a").Stack()) != 0 {
+		t.Error("stack should be empty by default")
+	}
+	err := ErrNotFound.WithStack()
+	if s := err.StackString(); !strings.Contains(s, "TestErrorStack") {
+		t.Errorf("stack should start at caller, got %s", s)
+	}
+	ErrorStack = true
+	defer func() { ErrorStack = false }()
+	if s := ErrNotFound.WithArgs("a").StackString(); !strings.Contains(s, "TestErrorStack") {
+		t.Errorf("stack should be captured when ErrorStack is on, got %s", s)
+	}
+}
+
+func TestErrorRegistry(t *testing.T) {
+	errBanned := Register(40399, "user banned", "账号已被封禁")
+	t.Cleanup(func() { unregisterError(40399) })
+	if errBanned.Code != 40399 || errBanned.Status() != 403 {
+		t.Errorf("unexpected registered error %v", errBanned)
+	}
+	info, ok := LookupError(40399)
+	if !ok || info.Desc != "账号已被封禁" || info.Status != 403 {
+		t.Errorf("unexpected registry entry %+v", info)
+	}
+	if info, ok := LookupError(40102); !ok || info.Message != "token expired" {
+		t.Errorf("builtin errors should be registered, got %+v", info)
+	}
+	list := Errors()
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Code >= list[i].Code {
+			t.Fatalf("catalog not sorted at %d", i)
+		}
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("duplicate code should panic")
+			}
+		}()
+		Register(40400, "another not found")
+	}()
+
+	r := NewRouter()
+	r.Get("/user/{id}", "get user", ErrNotFound, errBanned, func(x *X) error { return nil })
+	doc := r.Doc()
+	if len(doc.Routes) != 1 || len(doc.Routes[0].Errors) != 2 {
+		t.Fatalf("expected declared errors in doc, got %+v", doc.Routes)
+	}
+	if e := doc.Routes[0].Errors[1]; e.Code != 40399 || e.Desc != "账号已被封禁" || e.Status != 403 {
+		t.Errorf("unexpected doc error %+v", e)
+	}
+}
+
+func TestErrorLogDetailed(t *testing.T) {
+	var buf bytes.Buffer
+	l := zerolog.New(&buf)
+	err := ErrInternalServer.WithInternal("user %d", 7)
+	// 全局的 .Err 不输出内部信息, 只有 logv.Detailed 包装后才输出
+	l.Error().Err(err).Send()
+	if strings.Contains(buf.String(), "user 7") {
+		t.Errorf("plain Err leaked internals: %s", buf.String())
+	}
+	buf.Reset()
+	l.Error().Err(logv.Detailed(err)).Send()
+	if !strings.Contains(buf.String(), "internal: user 7") {
+		t.Errorf("detailed error missing internals: %s", buf.String())
+	}
+	if !errors.Is(logv.Detailed(err), ErrInternalServer) || logv.Detailed(nil) != nil {
+		t.Error("Detailed should unwrap to the original error")
+	}
+}
+
+// unregisterError 从错误码表中移除, 用于测试清理
+func unregisterError(code int) {
+	errorRegistryMu.Lock()
+	defer errorRegistryMu.Unlock()
+	delete(errorRegistry, code)
+}
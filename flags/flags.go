@@ -186,6 +186,29 @@ func LoadCfg(path string, cfg interface{}) {
 	}
 }
 
+// LoadCfgSection 从配置文件中读取 section 下的内容到 cfg, 如模块的配置段
+func LoadCfgSection(path string, section string, cfg interface{}) {
+	yamlFile, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logv.Warn().Msg(err.Error())
+		}
+		return
+	}
+	sections := map[string]yaml.Node{}
+	if err = yaml.Unmarshal(yamlFile, &sections); err != nil {
+		logv.Warn().Msg(err.Error())
+		return
+	}
+	node, ok := sections[section]
+	if !ok {
+		return
+	}
+	if err = node.Decode(cfg); err != nil {
+		logv.Warn().Msgf("section %s: %v", section, err)
+	}
+}
+
 // 会覆盖写入
 func DumpCfg(path string, cfg interface{}) error {
 	body, err := yaml.Marshal(cfg)
@@ -219,6 +219,13 @@ func (fs *Flags) AutoRegister(config any) {
 	fs.autoRegisterWithPrefix(config, "", "")
 }
 
+// AutoRegisterPrefix 在 prefix 下自动注册命令行参数, 如 prefix 为 user 时字段 db 对应参数 -user.db 和环境变量 USER_DB
+func (fs *Flags) AutoRegisterPrefix(config any, prefix string) {
+	godotenv.Load()
+	envPrefix := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(prefix))
+	fs.autoRegisterWithPrefix(config, envPrefix, prefix)
+}
+
 func (fs *Flags) registerValue(field reflect.Value, flagName, defaultValue, usage string) {
 	// 根据字段类型注册不同的参数类型
 	switch {
@@ -1,6 +1,7 @@
 package flags
 
 import (
+	"os"
 	"testing"
 )
 
@@ -111,3 +112,49 @@ func TestCustomTypes(t *testing.T) {
 		t.Errorf("expected enabled false, got %v", cfg.Enabled)
 	}
 }
+
+type ModuleConfig struct {
+	DSN  string `json:"dsn" yaml:"dsn"`
+	Size int    `json:"size" yaml:"size"`
+}
+
+func TestAutoRegisterPrefix(t *testing.T) {
+	t.Setenv("USER_MOD_SIZE", "7")
+	cfg := &ModuleConfig{DSN: "sqlite://user.db"}
+	f := New("test", "test flags")
+	f.AutoRegisterPrefix(cfg, "user-mod")
+
+	if f.Lookup("user-mod.dsn") == nil {
+		t.Fatal("flag 'user-mod.dsn' not registered")
+	}
+	if f.Lookup("dsn") != nil {
+		t.Error("flag 'dsn' should not be registered without prefix")
+	}
+	if cfg.Size != 7 {
+		t.Errorf("env USER_MOD_SIZE not applied, got %d", cfg.Size)
+	}
+	if err := f.Set("user-mod.dsn", "mysql://user"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DSN != "mysql://user" {
+		t.Errorf("got %s, want mysql://user", cfg.DSN)
+	}
+}
+
+func TestLoadCfgSection(t *testing.T) {
+	path := t.TempDir() + "/cfg.yaml"
+	body := "name: app\nuser:\n  dsn: sqlite://a.db\n  size: 3\n"
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ModuleConfig{Size: 1}
+	LoadCfgSection(path, "user", cfg)
+	if cfg.DSN != "sqlite://a.db" || cfg.Size != 3 {
+		t.Errorf("got %+v", cfg)
+	}
+	other := &ModuleConfig{Size: 1}
+	LoadCfgSection(path, "order", other)
+	if other.Size != 1 || other.DSN != "" {
+		t.Errorf("missing section changed cfg: %+v", other)
+	}
+}
@@ -0,0 +1,289 @@
+//
+// listener.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// unixPrefix Host 以 unix: 开头时监听 Unix socket, 如 unix:/run/app.sock
+	unixPrefix = "unix:"
+	// fdPrefix Host 以 fd: 开头时使用 systemd 传入的 socket, 如 fd:3 或 fd:http (LISTEN_FDNAMES)
+	fdPrefix = "fd:"
+	// listenFDsStart systemd socket activation 传入的第一个文件描述符
+	listenFDsStart = 3
+)
+
+var (
+	inheritOnce sync.Once
+	inherited   []inheritedListener
+	inheritErr  error
+)
+
+type inheritedListener struct {
+	fd   int
+	name string
+	l    net.Listener
+}
+
+// ListenFDs 返回 systemd socket activation (LISTEN_PID/LISTEN_FDS/LISTEN_FDNAMES) 传入的 listener,
+// 只解析一次并清除相关环境变量, 避免子进程继承
+func ListenFDs() ([]net.Listener, error) {
+	inheritOnce.Do(func() {
+		inherited, inheritErr = inheritListeners(listenFDsStart)
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	})
+	res := make([]net.Listener, len(inherited))
+	for i, l := range inherited {
+		res[i] = l.l
+	}
+	return res, inheritErr
+}
+
+func inheritListeners(start int) ([]inheritedListener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	res := make([]inheritedListener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := start + i
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return res, fmt.Errorf("inherit fd %d: %w", fd, err)
+		}
+		item := inheritedListener{fd: fd, l: l}
+		if i < len(names) {
+			item.name = names[i]
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+// lookupInherited 按 fd:3 / fd:name 选择继承的 listener, 为空时返回第一个
+func lookupInherited(sel string) (net.Listener, error) {
+	if _, err := ListenFDs(); err != nil {
+		return nil, err
+	}
+	for _, l := range inherited {
+		if sel == "" || sel == l.name || sel == strconv.Itoa(l.fd) {
+			return l.l, nil
+		}
+	}
+	if sel == "" {
+		return nil, errors.New("no inherited listener")
+	}
+	return nil, fmt.Errorf("inherited listener %q not found", sel)
+}
+
+// matchInherited 返回地址与 Config 相同的继承 listener, 没有时返回 nil
+func (c *Config) matchInherited() (net.Listener, error) {
+	if _, err := ListenFDs(); err != nil {
+		return nil, err
+	}
+	path, isUnix := strings.CutPrefix(c.Host, unixPrefix)
+	host := strings.Trim(c.Host, "[]")
+	ip := net.ParseIP(host)
+	if !isUnix && host != "" && ip == nil {
+		// 主机名不做解析匹配
+		return nil, nil
+	}
+	for _, l := range inherited {
+		switch addr := l.l.Addr().(type) {
+		case *net.UnixAddr:
+			if isUnix && addr.Name == path {
+				return l.l, nil
+			}
+		case *net.TCPAddr:
+			if isUnix || addr.Port != c.Port {
+				continue
+			}
+			if ip == nil && addr.IP.IsUnspecified() || ip != nil && ip.Equal(addr.IP) {
+				return l.l, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// listen 按 Config.Host 创建 listener: unix:/path 为 Unix socket, fd:xx 为继承的 socket,
+// 其余为 tcp; 存在地址相同的 socket activation 传入的 listener 时直接使用
+func (c *Config) listen() (net.Listener, error) {
+	if sel, ok := strings.CutPrefix(c.Host, fdPrefix); ok {
+		return lookupInherited(sel)
+	}
+	if l, err := c.matchInherited(); l != nil || err != nil {
+		return l, err
+	}
+	if path, ok := strings.CutPrefix(c.Host, unixPrefix); ok {
+		return listenUnix(path, c.UnixSocketMode)
+	}
+	return net.Listen("tcp", c.Url())
+}
+
+// listenUnix 清理残留的 socket 文件后监听, Close 时自动删除 socket 文件
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// listenerURL 返回用于日志的访问地址, 监听所有地址时显示 localhost
+func listenerURL(l net.Listener, https bool) string {
+	addr := l.Addr()
+	if addr.Network() == "unix" {
+		return unixPrefix + addr.String()
+	}
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	scheme := "http://"
+	if https {
+		scheme = "https://"
+	}
+	return scheme + net.JoinHostPort(host, port)
+}
+
+// Listener Application 的附加监听地址, 拥有独立的路由、TLS 和连接数限制,
+// 与主服务共用日志和优雅关闭
+//
+//	admin, _ := app.AddListener("admin", vigo.WithHost("127.0.0.1"), vigo.WithPort(9090))
+//	admin.Router().Get("/metrics", metrics)
+type Listener struct {
+	name      string
+	config    *Config
+	router    Router
+	server    *http.Server
+	tlsConfig *tls.Config
+	listener  net.Listener
+}
+
+// AddListener 添加监听地址, 需在 Run 之前调用; 默认沿用主服务的超时设置, 不继承 TLS 和 h2c
+func (app *Application) AddListener(name string, opts ...func(*Config)) (*Listener, error) {
+	h := app.config.HTTPConfig
+	h.TLSCertFile, h.TLSKeyFile, h.TLSClientCAFile, h.TLSClientCertOptional, h.H2C = "", "", "", false, false
+	c := &Config{
+		Host:           "0.0.0.0",
+		UnixSocketMode: 0o660,
+		DisableReqLog:  app.config.DisableReqLog,
+		AccessLog:      app.config.AccessLog,
+		HTTPConfig:     h,
+		BaseContext:    app.config.BaseContext,
+		ConnContext:    app.config.ConnContext,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := c.IsValid(); err != nil {
+		return nil, fmt.Errorf("listener %s: %w", name, err)
+	}
+	l := &Listener{name: name, config: c, router: NewRouter()}
+	var err error
+	l.server, l.tlsConfig, err = newHTTPServer(c, l)
+	if err != nil {
+		return nil, fmt.Errorf("listener %s: %w", name, err)
+	}
+	app.listeners = append(app.listeners, l)
+	return l, nil
+}
+
+func (l *Listener) Name() string {
+	return l.name
+}
+
+func (l *Listener) Router() Router {
+	return l.router
+}
+
+func (l *Listener) SetRouter(r Router) {
+	l.router = r
+}
+
+func (l *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if l.config.AccessLog != nil {
+		l.config.AccessLog.serve(w, r, l.router.ServeHTTP)
+		return
+	}
+	if !l.config.DisableReqLog {
+		defer logRequest(r, nanotime())
+	}
+	l.router.ServeHTTP(w, r)
+}
+
+func (l *Listener) netListener() (net.Listener, error) {
+	if l.listener != nil {
+		return l.listener, nil
+	}
+	ln, err := listen(l.config, l.tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("listener %s: %w", l.name, err)
+	}
+	l.listener = ln
+	return ln, nil
+}
+
+// RedirectHTTPS 将请求重定向到 https, port 为 0 或 443 时省略端口
+//
+//	redirect, _ := app.AddListener("redirect", vigo.WithPort(80))
+//	redirect.Router().Any("/**", vigo.RedirectHTTPS(443))
+func RedirectHTTPS(port int) func(*X) {
+	return func(x *X) {
+		host := x.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != 0 && port != 443 {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+		}
+		http.Redirect(x.ResponseWriter(), x.Request, "https://"+host+x.Request.URL.RequestURI(), http.StatusPermanentRedirect)
+	}
+}
@@ -0,0 +1,267 @@
+package vigo
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigIsValid(t *testing.T) {
+	cases := map[string]bool{
+		"0.0.0.0":            true,
+		"127.0.0.1":          true,
+		"::":                 true,
+		"[::1]":              true,
+		"fe80::1":            true,
+		"localhost":          true,
+		"api.example.com":    true,
+		"":                   true,
+		"unix:/run/app.sock": true,
+		"fd:3":               true,
+		"unix:":              false,
+		"bad host":           false,
+		"-bad.example.com":   false,
+		"256.1.1.1.1:80":     false,
+	}
+	for host, want := range cases {
+		c := &Config{Host: host, Port: 8000}
+		if err := c.IsValid(); (err == nil) != want {
+			t.Errorf("IsValid(%q) = %v, want valid=%v", host, err, want)
+		}
+	}
+	if u := (&Config{Host: "::1", Port: 80}).Url(); u != "[::1]:80" {
+		t.Errorf("unexpected ipv6 url %q", u)
+	}
+	if u := (&Config{Host: "[::1]", Port: 80}).Url(); u != "[::1]:80" {
+		t.Errorf("unexpected bracketed ipv6 url %q", u)
+	}
+}
+
+func TestUnixListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	// 残留的 socket 文件会被清理
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	app, err := NewServer(WithHost("unix:"+path), WithUnixSocketMode(0o600), WithDocPath(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Router().Get("/ping", func(x *X) { x.WriteString("pong") })
+	if _, err := app.netListener(); err != nil {
+		t.Fatal(err)
+	}
+	go app.RunContext(context.Background())
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("unexpected socket mode %v", fi.Mode().Perm())
+	}
+	// 正在使用的 socket 不能被抢占
+	if _, err := listenUnix(path, 0); err == nil {
+		t.Error("expected error for socket in use")
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "pong" {
+		t.Errorf("unexpected body %q", b)
+	}
+
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file should be removed, got %v", err)
+	}
+}
+
+func TestInheritListeners(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+	if res, _ := inheritListeners(int(f.Fd())); len(res) != 0 {
+		t.Error("should ignore LISTEN_FDS of other process")
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDNAMES", "http")
+	res, err := inheritListeners(int(f.Fd()))
+	if err != nil || len(res) != 1 {
+		t.Fatalf("expected one listener, got %v %v", res, err)
+	}
+	defer res[0].l.Close()
+	if res[0].name != "http" || res[0].l.Addr().String() != l.Addr().String() {
+		t.Errorf("unexpected listener %+v", res[0])
+	}
+}
+
+func TestListenInherited(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ListenFDs()
+	old := inherited
+	inherited = []inheritedListener{{fd: 3, name: "http", l: l}}
+	defer func() { inherited = old }()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	for _, c := range []Config{{Host: "fd:"}, {Host: "fd:3"}, {Host: "fd:http"}, {Host: "127.0.0.1", Port: port}} {
+		got, err := c.listen()
+		if err != nil || got != l {
+			t.Errorf("%+v: expected inherited listener, got %v %v", c, got, err)
+		}
+	}
+	if _, err := (&Config{Host: "fd:admin"}).listen(); err == nil {
+		t.Error("unknown fd name should fail")
+	}
+	// 地址不同的 listener 不能拿到继承的 socket
+	other, err := (&Config{Host: "127.0.0.1", Port: 0}).listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if other == l {
+		t.Error("listener with other address got inherited socket")
+	}
+}
+
+func TestMultipleListeners(t *testing.T) {
+	app, base := newTestServer(t)
+	app.Router().Get("/api", func(x *X) { x.WriteString("api") })
+
+	admin, err := app.AddListener("admin", WithHost("127.0.0.1"), WithPort(1), WithMaxConnections(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	admin.Router().Get("/metrics", func(x *X) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		x.WriteString("metrics")
+	})
+	redirect, err := app.AddListener("redirect", WithHost("127.0.0.1"), WithPort(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect.Router().Any("/**", RedirectHTTPS(8443))
+	for _, l := range []*Listener{admin, redirect} {
+		if l.listener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	adminBase, redirectBase := "http://"+admin.listener.Addr().String(), "http://"+redirect.listener.Addr().String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- app.RunContext(ctx) }()
+
+	get := func(url string) (*http.Response, string) {
+		c := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := c.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+	if _, b := get(base + "/api"); b != "api" {
+		t.Errorf("unexpected api body %q", b)
+	}
+	if resp, _ := get(adminBase + "/api"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("routers should be isolated, got %d", resp.StatusCode)
+	}
+	resp, _ := get(redirectBase + "/login?next=/")
+	if loc := resp.Header.Get("Location"); resp.StatusCode != http.StatusPermanentRedirect || loc != "https://127.0.0.1:8443/login?next=/" {
+		t.Errorf("unexpected redirect %d %q", resp.StatusCode, loc)
+	}
+
+	// 共用优雅关闭
+	body := make(chan string, 1)
+	go func() {
+		_, b := get(adminBase + "/metrics")
+		body <- b
+	}()
+	<-started
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Fatal(err)
+	}
+	if b := <-body; b != "metrics" {
+		t.Errorf("in-flight admin request should complete, got %q", b)
+	}
+	if _, err := http.Get(adminBase + "/metrics"); err == nil {
+		t.Error("admin listener should be closed")
+	}
+}
+
+func TestListenerBindError(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	app, base := newTestServer(t)
+	if _, err := app.AddListener("admin", WithHost("127.0.0.1"), WithPort(port)); err != nil {
+		t.Fatal(err)
+	}
+	stopped := false
+	app.OnShutdown("event", func(context.Context) error {
+		stopped = true
+		return nil
+	})
+	if err := app.RunContext(context.Background()); err == nil || !strings.Contains(err.Error(), "listener admin") {
+		t.Errorf("expected bind error, got %v", err)
+	}
+	if !stopped {
+		t.Error("shutdown hooks should run after bind error")
+	}
+	if _, err := http.Get(base); err == nil {
+		t.Error("primary listener should be closed after bind error")
+	}
+	if _, err := app.AddListener("bad", WithHost("bad host"), WithPort(80)); err == nil {
+		t.Error("expected invalid host error")
+	}
+	// 未指定端口时报错, 而不是监听随机端口
+	if _, err := app.AddListener("admin", WithHost("127.0.0.1")); err == nil || !strings.Contains(err.Error(), "port is required") {
+		t.Errorf("expected port required error, got %v", err)
+	}
+}
@@ -234,6 +234,28 @@ func PanicTrace() []byte {
 	return buf[:n]
 }
 
+// Detailed 包装 err, 日志中按 %+v 输出, 用于记录 vigo.Error 的内部信息和原始错误, 不影响全局的 zerolog 设置
+//
+//	logv.Error().Err(logv.Detailed(err)).Send()
+func Detailed(err error) error {
+	if err == nil {
+		return nil
+	}
+	return detailedError{err}
+}
+
+type detailedError struct {
+	err error
+}
+
+func (e detailedError) Error() string {
+	return fmt.Sprintf("%+v", e.err)
+}
+
+func (e detailedError) Unwrap() error {
+	return e.err
+}
+
 func RecoverErr() error {
 	if e := recover(); e != nil {
 		if e, ok := e.(error); ok {
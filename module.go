@@ -0,0 +1,222 @@
+//
+// module.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/veypi/vigo/flags"
+	"gopkg.in/yaml.v3"
+)
+
+// Module 可组合的业务模块, 通过 App.AddModule 添加, 按需实现以下可选接口:
+//
+//	ModuleConfig   配置段, 对应配置文件中 Name() 下的内容和参数 -name.field
+//	ModuleDepender 依赖的其他模块, 被依赖的模块先初始化和启动
+//	ModuleIniter   初始化, 如数据库迁移
+//	ModuleRouter   注册路由
+//	ModuleStarter  服务启动前执行, 如启动后台任务
+//	ModuleStopper  优雅关闭时执行, 按启动的相反顺序
+type Module interface {
+	Name() string
+}
+
+type ModuleConfig interface {
+	// Config 返回配置结构体指针
+	Config() any
+}
+
+type ModuleDepender interface {
+	DependsOn() []string
+}
+
+type ModuleIniter interface {
+	Init() error
+}
+
+type ModuleRouter interface {
+	Routes(r Router)
+}
+
+type ModuleStarter interface {
+	OnStart(ctx context.Context) error
+}
+
+type ModuleStopper interface {
+	OnStop(ctx context.Context) error
+}
+
+// sortModules 按依赖关系排序, 被依赖的模块在前, 无依赖关系的模块保持添加顺序
+func sortModules(modules []Module) ([]Module, error) {
+	byName := make(map[string]Module, len(modules))
+	for _, m := range modules {
+		if _, ok := byName[m.Name()]; ok {
+			return nil, fmt.Errorf("duplicate module %s", m.Name())
+		}
+		byName[m.Name()] = m
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(modules))
+	res := make([]Module, 0, len(modules))
+	var path []string
+	var visit func(m Module) error
+	visit = func(m Module) error {
+		name := m.Name()
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			i := 0
+			for path[i] != name {
+				i++
+			}
+			return fmt.Errorf("module dependency cycle: %s -> %s", strings.Join(path[i:], " -> "), name)
+		}
+		state[name] = visiting
+		path = append(path, name)
+		if d, ok := m.(ModuleDepender); ok {
+			for _, dep := range d.DependsOn() {
+				dm, ok := byName[dep]
+				if !ok {
+					return fmt.Errorf("module %s depends on unknown module %s", name, dep)
+				}
+				if err := visit(dm); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		res = append(res, m)
+		return nil
+	}
+	for _, m := range modules {
+		if err := visit(m); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// moduleConfig 返回模块的配置结构体, 未实现 ModuleConfig 或返回 nil (含 nil 指针) 时为 nil
+func moduleConfig(m Module) any {
+	c, ok := m.(ModuleConfig)
+	if !ok {
+		return nil
+	}
+	cfg := c.Config()
+	if cfg == nil {
+		return nil
+	}
+	switch v := reflect.ValueOf(cfg); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	return cfg
+}
+
+// checkModuleNames 带配置的模块名不能与 cfgs 顶层的配置项重复, 否则配置文件和参数中会出现重复的键
+func checkModuleNames(modules []Module, cfgs ...any) error {
+	keys := map[string]bool{}
+	for _, cfg := range cfgs {
+		for k := range configKeys(cfg) {
+			keys[k] = true
+		}
+	}
+	for _, m := range modules {
+		if moduleConfig(m) != nil && keys[m.Name()] {
+			return fmt.Errorf("module %s conflicts with existing config key", m.Name())
+		}
+	}
+	return nil
+}
+
+// registerModuleFlags 以模块名为前缀注册配置参数
+func registerModuleFlags(cmd *flags.Flags, modules []Module) {
+	for _, m := range modules {
+		if cfg := moduleConfig(m); cfg != nil {
+			cmd.AutoRegisterPrefix(cfg, m.Name())
+		}
+	}
+}
+
+// loadModuleConfigs 读取配置文件中各模块同名的配置段
+func loadModuleConfigs(path string, modules []Module) {
+	for _, m := range modules {
+		if cfg := moduleConfig(m); cfg != nil {
+			flags.LoadCfgSection(path, m.Name(), cfg)
+		}
+	}
+}
+
+// configNode 合并各配置结构体的 yaml 并追加各模块的配置段, 用于生成配置文件
+func configNode(modules []Module, cfgs ...any) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, cfg := range cfgs {
+		v := &yaml.Node{}
+		if err := v.Encode(cfg); err != nil {
+			return nil, err
+		}
+		if v.Kind == yaml.MappingNode {
+			node.Content = append(node.Content, v.Content...)
+		}
+	}
+	for _, m := range modules {
+		cfg := moduleConfig(m)
+		if cfg == nil {
+			continue
+		}
+		v := &yaml.Node{}
+		if err := v.Encode(cfg); err != nil {
+			return nil, fmt.Errorf("module %s: %w", m.Name(), err)
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: m.Name()}, v)
+	}
+	return node, nil
+}
+
+// initModules 按依赖顺序初始化模块并注册路由
+func initModules(modules []Module, r Router) error {
+	for _, m := range modules {
+		if i, ok := m.(ModuleIniter); ok {
+			if err := i.Init(); err != nil {
+				return fmt.Errorf("init module %s: %w", m.Name(), err)
+			}
+		}
+	}
+	for _, m := range modules {
+		if mr, ok := m.(ModuleRouter); ok {
+			mr.Routes(r)
+		}
+	}
+	return nil
+}
+
+// startModules 按依赖顺序启动模块, 启动成功后注册 OnStop, 关闭时按相反顺序执行;
+// 启动失败时已启动模块的 OnStop 已注册到 server, 由调用方执行 Shutdown
+func startModules(ctx context.Context, server *Application, modules []Module) error {
+	for _, m := range modules {
+		if s, ok := m.(ModuleStarter); ok {
+			if err := s.OnStart(ctx); err != nil {
+				return fmt.Errorf("start module %s: %w", m.Name(), err)
+			}
+		}
+		if s, ok := m.(ModuleStopper); ok {
+			server.OnShutdown("module "+m.Name(), s.OnStop)
+		}
+	}
+	return nil
+}
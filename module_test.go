@@ -0,0 +1,206 @@
+package vigo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type testModuleConfig struct {
+	DSN string `json:"dsn" yaml:"dsn"`
+}
+
+type testModule struct {
+	name     string
+	deps     []string
+	cfg      *testModuleConfig
+	startErr error
+	log      *[]string
+}
+
+func (m *testModule) Name() string        { return m.name }
+func (m *testModule) DependsOn() []string { return m.deps }
+func (m *testModule) Config() any         { return m.cfg }
+
+func (m *testModule) Init() error {
+	*m.log = append(*m.log, "init "+m.name)
+	return nil
+}
+
+func (m *testModule) Routes(r Router) {
+	r.Get("/"+m.name, func(x *X) {
+		x.WriteString(m.name)
+	})
+}
+
+func (m *testModule) OnStart(ctx context.Context) error {
+	*m.log = append(*m.log, "start "+m.name)
+	return m.startErr
+}
+
+func (m *testModule) OnStop(ctx context.Context) error {
+	*m.log = append(*m.log, "stop "+m.name)
+	return nil
+}
+
+func moduleNames(modules []Module) string {
+	names := make([]string, len(modules))
+	for i, m := range modules {
+		names[i] = m.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSortModules(t *testing.T) {
+	var log []string
+	mod := func(name string, deps ...string) Module {
+		return &testModule{name: name, deps: deps, log: &log}
+	}
+	sorted, err := sortModules([]Module{mod("order", "user", "db"), mod("user", "db"), mod("db"), mod("mail")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := moduleNames(sorted); got != "db,user,order,mail" {
+		t.Errorf("got %s", got)
+	}
+
+	_, err = sortModules([]Module{mod("a", "b"), mod("b", "c"), mod("c", "a")})
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Errorf("cycle not reported: %v", err)
+	}
+	_, err = sortModules([]Module{mod("a", "missing")})
+	if err == nil || !strings.Contains(err.Error(), "unknown module missing") {
+		t.Errorf("unknown dependency not reported: %v", err)
+	}
+	_, err = sortModules([]Module{mod("a"), mod("a")})
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("duplicate not reported: %v", err)
+	}
+}
+
+func TestModuleLifecycle(t *testing.T) {
+	var log []string
+	modules, err := sortModules([]Module{
+		&testModule{name: "user", deps: []string{"db"}, log: &log},
+		&testModule{name: "db", log: &log},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, base := newTestServer(t)
+	if err := initModules(modules, app.Router()); err != nil {
+		t.Fatal(err)
+	}
+	if err := startModules(context.Background(), app, modules); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.RunContext(ctx) }()
+
+	resp, err := http.Get(base + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "user" {
+		t.Errorf("got %q", body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := "init db,init user,start db,start user,stop user,stop db"
+	if got := strings.Join(log, ","); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestModuleStartFailure(t *testing.T) {
+	var log []string
+	modules := []Module{
+		&testModule{name: "db", log: &log},
+		&testModule{name: "user", startErr: errors.New("boom"), log: &log},
+		&testModule{name: "order", log: &log},
+	}
+	app, _ := newTestServer(t)
+	err := startModules(context.Background(), app, modules)
+	if err == nil || !strings.Contains(err.Error(), "start module user: boom") {
+		t.Fatalf("got %v", err)
+	}
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 只停止已启动成功的模块
+	if got := strings.Join(log, ","); got != "start db,start user,stop db" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestModuleConfigNode(t *testing.T) {
+	appCfg := &struct {
+		Name string `yaml:"name"`
+	}{Name: "demo"}
+	modules := []Module{&testModule{name: "user", cfg: &testModuleConfig{DSN: "sqlite://user.db"}}}
+	node, err := configNode(modules, appCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := yaml.Marshal(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name: demo\nuser:\n    dsn: sqlite://user.db\n"
+	if string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+}
+
+func TestModuleConfigNil(t *testing.T) {
+	// Config 返回 nil 指针的模块不注册参数, 也不生成配置段
+	m := &testModule{name: "user"}
+	if cfg := moduleConfig(m); cfg != nil {
+		t.Fatalf("typed nil config should be ignored, got %#v", cfg)
+	}
+	node, err := configNode([]Module{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node.Content) != 0 {
+		t.Errorf("unexpected section %v", node.Content)
+	}
+}
+
+func TestCheckModuleNames(t *testing.T) {
+	appCfg := &struct {
+		Name  string `yaml:"name"`
+		Cache string `json:"cache,omitempty" yaml:"cache,omitempty"`
+	}{Name: "demo"}
+	httpCfg := &struct {
+		Server HTTPConfig `yaml:"server"`
+	}{Server: DefaultHTTPConfig()}
+	mod := func(name string) Module {
+		return &testModule{name: name, cfg: &testModuleConfig{}}
+	}
+	if err := checkModuleNames([]Module{mod("user")}, appCfg, httpCfg); err != nil {
+		t.Error(err)
+	}
+	// 未设置的 omitempty 字段同样占用配置项和参数名
+	for _, name := range []string{"server", "name", "cache"} {
+		err := checkModuleNames([]Module{mod(name)}, appCfg, httpCfg)
+		if err == nil || !strings.Contains(err.Error(), "conflicts") {
+			t.Errorf("%s: collision not reported: %v", name, err)
+		}
+	}
+	// 没有配置的模块不会生成配置段
+	if err := checkModuleNames([]Module{&testModule{name: "server"}}, appCfg, httpCfg); err != nil {
+		t.Error(err)
+	}
+}
@@ -130,6 +130,7 @@ type RouteHandler struct {
 	Args         any
 	Response     any
 	ArgsDesc     string
+	Errors       []*Error // 声明该路由可能返回的错误, 用于文档
 }
 
 // String() => /router/path
@@ -284,8 +285,10 @@ func (r *route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 		x.fcs = fcs
 		x.fcsInfo = infos
+		x.route = subR
 		x.routeVars = subR.varsCache
 		x.Next()
+		recordAccess(req, x)
 	} else {
 		x.WriteHeader(404)
 	}
@@ -512,6 +515,7 @@ func (r *route) SetVar(key string, value any) Router {
 //   - Functions: The actual route handlers (middleware or final handler).
 //   - String: Treated as the API description/summary.
 //   - Struct (or pointer to struct): Treated as the input parameter schema/description.
+//   - *Error: Declares an error the endpoint can return, listed in Doc().
 func (r *route) Set(prefix string, method string, handlers ...any) Router {
 	method = strings.ToUpper(method)
 	logv.Assert(slices.Contains(allowedMethods, method), fmt.Sprintf("not support HTTP method: %v", method))
@@ -527,6 +531,7 @@ func (r *route) Set(prefix string, method string, handlers ...any) Router {
 	desarg := ""
 	var args any
 	var response any
+	var errs []*Error
 	filterHandlers := make([]any, 0, len(handlers))
 	filterHandlersInfo := make([]*HandlerInfo, 0, len(handlers))
 	file, line := getHandlerLocation()
@@ -558,6 +563,10 @@ func (r *route) Set(prefix string, method string, handlers ...any) Router {
 			desc = s
 			continue
 		}
+		if e, ok := fc.(*Error); ok {
+			errs = append(errs, e)
+			continue
+		}
 
 		// try to standardize
 		var std FuncX2AnyErr
@@ -585,6 +594,10 @@ func (r *route) Set(prefix string, method string, handlers ...any) Router {
 				if resType.Kind() == reflect.Ptr {
 					resType = resType.Elem()
 				}
+				// 流式响应按数组描述
+				if elem, ok := streamElemType(resType); ok {
+					resType = reflect.SliceOf(elem)
+				}
 				k := resType.Kind()
 				if k == reflect.Struct || k == reflect.Slice || k == reflect.Array || k == reflect.Map ||
 					(k >= reflect.Bool && k <= reflect.Float64) || k == reflect.String {
@@ -631,6 +644,7 @@ func (r *route) Set(prefix string, method string, handlers ...any) Router {
 		Args:         args,
 		Response:     response,
 		ArgsDesc:     desarg,
+		Errors:       errs,
 	}
 	node.syncCache()
 	return node
@@ -8,20 +8,32 @@
 package vigo
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/veypi/vigo/logv"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"golang.org/x/net/netutil"
 )
 
 func NewServer(opts ...func(*Config)) (*Application, error) {
 	c := &Config{
-		Host:    "0.0.0.0",
-		Port:    8000,
-		DocPath: "_api",
+		Host:           "0.0.0.0",
+		Port:           8000,
+		DocPath:        "_api",
+		UnixSocketMode: 0o660,
+		HTTPConfig:     DefaultHTTPConfig(),
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -30,36 +42,94 @@ func NewServer(opts ...func(*Config)) (*Application, error) {
 		return nil, err
 	}
 	app := &Application{
-		config: c,
-		router: NewRouter(),
+		config:       c,
+		router:       NewRouter(),
+		shutdownDone: make(chan struct{}),
 	}
-	app.server = &http.Server{
+	var err error
+	app.server, app.tlsConfig, err = newHTTPServer(c, app)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// newHTTPServer 按配置创建 http.Server, 返回启用 TLS 时的配置
+func newHTTPServer(c *Config, h http.Handler) (*http.Server, *tls.Config, error) {
+	tlsCfg, err := c.tlsConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	server := &http.Server{
 		Addr:              c.Url(),
-		TLSConfig:         c.TlsCfg,
-		ReadTimeout:       0,
-		ReadHeaderTimeout: 0,
-		WriteTimeout:      0,
-		IdleTimeout:       0,
-		MaxHeaderBytes:    0,
+		TLSConfig:         tlsCfg,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
 		TLSNextProto:      nil,
 		ConnState:         nil,
 		ErrorLog:          nil,
-		// TODO
-		BaseContext: nil,
-		// TODO
-		ConnContext: nil,
+		BaseContext:       c.BaseContext,
+		ConnContext:       c.ConnContext,
 	}
-	app.server.Handler = app
-
-	return app, nil
+	if len(c.TrustedProxies) > 0 {
+		proxies, err := ParseTrustedProxies(c.TrustedProxies...)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case c.ForwardedHeader == "" || strings.EqualFold(c.ForwardedHeader, HeaderXForwardedFor):
+			proxies.Header = HeaderXForwardedFor
+		case strings.EqualFold(c.ForwardedHeader, HeaderForwarded):
+			proxies.Header = HeaderForwarded
+		default:
+			return nil, nil, fmt.Errorf("invalid forwarded header %q", c.ForwardedHeader)
+		}
+		base := c.BaseContext
+		server.BaseContext = func(l net.Listener) context.Context {
+			ctx := context.Background()
+			if base != nil {
+				ctx = base(l)
+			}
+			return context.WithValue(ctx, TrustedProxiesKey, proxies)
+		}
+	}
+	server.SetKeepAlivesEnabled(!c.DisableKeepAlives)
+	server.Handler = h
+	if c.H2C && tlsCfg == nil {
+		// 同时支持 prior knowledge 和 Upgrade: h2c, 连接由 http2.Server 接管并参与优雅关闭
+		h2s := &http2.Server{IdleTimeout: c.IdleTimeout}
+		if err := http2.ConfigureServer(server, h2s); err != nil {
+			return nil, nil, err
+		}
+		server.Handler = h2c.NewHandler(h, h2s)
+	}
+	return server, tlsCfg, nil
 }
 
 type Application struct {
-	router   Router
-	muxs     []func(http.ResponseWriter, *http.Request) func(http.ResponseWriter, *http.Request)
-	config   *Config
-	server   *http.Server
-	listener net.Listener
+	router    Router
+	muxs      []func(http.ResponseWriter, *http.Request) func(http.ResponseWriter, *http.Request)
+	config    *Config
+	server    *http.Server
+	tlsConfig *tls.Config
+	listener  net.Listener
+	// listeners 通过 AddListener 添加的其他监听地址
+	listeners []*Listener
+
+	hooksMu      sync.Mutex
+	hooks        []shutdownHook
+	before       []func()
+	shutdownOnce sync.Once
+	shutdownDone chan struct{}
+	shutdownErr  error
+}
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
 }
 
 func (app *Application) SetMux(m func(w http.ResponseWriter, r *http.Request) func(http.ResponseWriter, *http.Request)) {
@@ -89,12 +159,17 @@ func (app *Application) Domain(d string) Router {
 }
 
 func (app *Application) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if app.config.AccessLog != nil {
+		app.config.AccessLog.serve(w, r, app.serve)
+		return
+	}
 	if !app.config.DisableReqLog {
-		start := nanotime()
-		defer func() {
-			logv.WithNoCaller.Debug().Int64("ms", (nanotime()-start)/1e6).Str("method", r.Method).Msg(r.RequestURI)
-		}()
+		defer logRequest(r, nanotime())
 	}
+	app.serve(w, r)
+}
+
+func (app *Application) serve(w http.ResponseWriter, r *http.Request) {
 	if len(app.muxs) == 0 {
 		app.router.ServeHTTP(w, r)
 		return
@@ -108,6 +183,10 @@ func (app *Application) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	app.router.ServeHTTP(w, r)
 }
 
+func logRequest(r *http.Request, start int64) {
+	logv.WithNoCaller.Debug().Int64("ms", (nanotime()-start)/1e6).Str("method", r.Method).Msg(r.RequestURI)
+}
+
 func (app *Application) Router() Router {
 	return app.router
 }
@@ -116,34 +195,163 @@ func (app *Application) SetRouter(r Router) {
 	app.router = r
 }
 
+// Run 启动服务, 收到 SIGINT/SIGTERM 时优雅关闭
 func (app *Application) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return app.RunContext(ctx)
+}
+
+// RunContext 启动服务, ctx 结束时停止接收新连接, 在 ShutdownTimeout 内等待请求处理完成,
+// 然后按注册的相反顺序执行 OnShutdown 注册的函数
+func (app *Application) RunContext(ctx context.Context) error {
 	app.EnableApiDoc()
 	l, e := app.netListener()
 	if e != nil {
-		return e
+		// 未能启动时同样执行关闭函数, 释放后台任务和连接池
+		return errors.Join(e, app.Shutdown(context.Background()))
+	}
+	bound := []net.Listener{l}
+	for _, ln := range app.listeners {
+		nl, err := ln.netListener()
+		if err != nil {
+			for _, b := range bound {
+				b.Close()
+			}
+			return errors.Join(err, app.Shutdown(context.Background()))
+		}
+		bound = append(bound, nl)
+	}
+	logv.WithNoCaller.Info().Msgf("start on %s ", listenerURL(l, app.tlsConfig != nil))
+	errc := make(chan error, 1+len(app.listeners))
+	go func() {
+		errc <- app.server.Serve(l)
+	}()
+	for i, ln := range app.listeners {
+		logv.WithNoCaller.Info().Msgf("start %s on %s ", ln.name, listenerURL(bound[i+1], ln.tlsConfig != nil))
+		go func() {
+			err := ln.server.Serve(bound[i+1])
+			if !errors.Is(err, http.ErrServerClosed) {
+				err = fmt.Errorf("listener %s: %w", ln.name, err)
+			}
+			errc <- err
+		}()
 	}
-	host := app.config.Host
-	if host == "0.0.0.0" {
-		host = "localhost"
+	select {
+	case err := <-errc:
+		if errors.Is(err, http.ErrServerClosed) {
+			// 由 Shutdown 关闭, 等待其完成
+			<-app.shutdownDone
+			return app.shutdownErr
+		}
+		return errors.Join(err, app.Shutdown(context.Background()))
+	case <-ctx.Done():
+	}
+	logv.WithNoCaller.Info().Msg("shutting down")
+	sctx := context.Background()
+	if app.config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		sctx, cancel = context.WithTimeout(sctx, app.config.ShutdownDelay+app.config.ShutdownTimeout)
+		defer cancel()
 	}
-	logv.WithNoCaller.Info().Msgf("start on http://%s:%d ", host, app.config.Port)
-	return app.server.Serve(l)
+	return app.Shutdown(sctx)
+}
+
+// OnShutdown 注册关闭时执行的函数, 如停止后台任务、关闭数据库连接池, 按注册的相反顺序执行
+func (app *Application) OnShutdown(name string, fn func(ctx context.Context) error) {
+	app.hooksMu.Lock()
+	defer app.hooksMu.Unlock()
+	app.hooks = append(app.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// BeforeShutdown 注册开始关闭时立即执行的函数, 如将 readiness 置为不可用, 在 ShutdownDelay 和停止接收连接之前执行
+func (app *Application) BeforeShutdown(fn func()) {
+	app.hooksMu.Lock()
+	defer app.hooksMu.Unlock()
+	app.before = append(app.before, fn)
+}
+
+// Shutdown 停止接收新连接并等待请求处理完成, 然后执行关闭函数, 多次调用只执行一次
+func (app *Application) Shutdown(ctx context.Context) error {
+	app.shutdownOnce.Do(func() {
+		app.hooksMu.Lock()
+		before := append([]func(){}, app.before...)
+		app.hooksMu.Unlock()
+		for _, fn := range before {
+			fn()
+		}
+		if d := app.config.ShutdownDelay; d > 0 {
+			// 等待负载均衡摘除实例后再停止接收连接
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+			}
+		}
+		errs := app.drain(ctx)
+		app.hooksMu.Lock()
+		hooks := append([]shutdownHook(nil), app.hooks...)
+		app.hooksMu.Unlock()
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := hooks[i].fn(ctx); err != nil {
+				logv.WithNoCaller.Warn().Err(logv.Detailed(err)).Msgf("shutdown %s failed", hooks[i].name)
+				errs = append(errs, fmt.Errorf("shutdown %s: %w", hooks[i].name, err))
+			}
+		}
+		app.shutdownErr = errors.Join(errs...)
+		close(app.shutdownDone)
+	})
+	<-app.shutdownDone
+	return app.shutdownErr
+}
+
+// drain 同时关闭所有监听地址并等待请求处理完成
+func (app *Application) drain(ctx context.Context) []error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	stop := func(name string, server *http.Server) {
+		defer wg.Done()
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("drain %srequests: %w", name, err))
+			mu.Unlock()
+		}
+	}
+	wg.Add(1 + len(app.listeners))
+	go stop("", app.server)
+	for _, ln := range app.listeners {
+		go stop(ln.name+" ", ln.server)
+	}
+	wg.Wait()
+	return errs
 }
 
 func (app *Application) netListener() (net.Listener, error) {
 	if app.listener != nil {
 		return app.listener, nil
 	}
-	l, err := net.Listen("tcp", app.config.Url())
+	l, err := listen(app.config, app.tlsConfig)
 	if err != nil {
 		return nil, err
 	}
-	if app.config.TlsCfg != nil && len(app.config.TlsCfg.Certificates) > 0 && app.config.TlsCfg.GetCertificate != nil {
-		l = tls.NewListener(l, app.config.TlsCfg)
-	}
-	if app.config.MaxConnections > 0 {
-		l = netutil.LimitListener(l, app.config.MaxConnections)
-	}
 	app.listener = l
 	return app.listener, nil
 }
+
+// listen 创建 listener 并按配置包装 TLS 和连接数限制
+func listen(c *Config, tlsCfg *tls.Config) (net.Listener, error) {
+	l, err := c.listen()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		l = tls.NewListener(l, tlsCfg)
+	}
+	if c.MaxConnections > 0 {
+		l = netutil.LimitListener(l, c.MaxConnections)
+	}
+	return l, nil
+}
@@ -0,0 +1,370 @@
+package vigo
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestServer(t *testing.T, opts ...func(*Config)) (*Application, string) {
+	app, err := NewServer(append([]func(*Config){WithDocPath("")}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.listener = l
+	return app, "http://" + l.Addr().String()
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	app, base := newTestServer(t)
+	started := make(chan struct{})
+	app.Router().Get("/slow", func(x *X) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		x.WriteString("done")
+	})
+	var mu sync.Mutex
+	var order []string
+	for _, name := range []string{"redis", "db", "event"} {
+		app.OnShutdown(name, func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- app.RunContext(ctx) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+
+	if err := <-runErr; err != nil {
+		t.Fatalf("expected clean shutdown, got %v", err)
+	}
+	if b := <-body; b != "done" {
+		t.Errorf("in-flight request should complete, got %q", b)
+	}
+	if strings.Join(order, ",") != "event,db,redis" {
+		t.Errorf("hooks should run in reverse order, got %v", order)
+	}
+	if _, err := http.Get(base + "/slow"); err == nil {
+		t.Error("server should stop accepting connections")
+	}
+	// 重复调用返回相同结果
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Errorf("second shutdown returned %v", err)
+	}
+}
+
+func TestGracefulShutdownTimeout(t *testing.T) {
+	app, base := newTestServer(t, WithShutdownTimeout(50*time.Millisecond))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	app.Router().Get("/hang", func(x *X) {
+		close(started)
+		<-release
+	})
+	hookErr := errors.New("close failed")
+	app.OnShutdown("db", func(ctx context.Context) error { return hookErr })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- app.RunContext(ctx) }()
+	go http.Get(base + "/hang")
+	<-started
+	cancel()
+
+	err := <-runErr
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, hookErr) {
+		t.Errorf("expected drain timeout and hook error, got %v", err)
+	}
+}
+
+func TestShutdownStopsRun(t *testing.T) {
+	app, _ := newTestServer(t)
+	runErr := make(chan error, 1)
+	go func() { runErr <- app.RunContext(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("expected nil from RunContext, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("RunContext did not return after Shutdown")
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	app, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := app.server
+	if s.ReadHeaderTimeout != 10*time.Second || s.ReadTimeout != 30*time.Second ||
+		s.WriteTimeout != 60*time.Second || s.IdleTimeout != 120*time.Second || s.MaxHeaderBytes != 1<<20 {
+		t.Errorf("unexpected defaults: %+v", s)
+	}
+	app, err = NewServer(WithReadTimeout(time.Second), WithWriteTimeout(2*time.Second), WithMaxHeaderBytes(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := app.server; s.ReadTimeout != time.Second || s.WriteTimeout != 2*time.Second ||
+		s.MaxHeaderBytes != 4096 || s.ReadHeaderTimeout != 10*time.Second {
+		t.Errorf("options not applied: %+v", s)
+	}
+
+	// 生成的配置文件中时长为可读格式, 并能读回
+	b, err := yaml.Marshal(map[string]HTTPConfig{"server": DefaultHTTPConfig()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "read_header_timeout: 10s") || strings.Contains(string(b), "tls_cert_file") {
+		t.Errorf("unexpected yaml %s", b)
+	}
+	var back map[string]HTTPConfig
+	if err := yaml.Unmarshal(b, &back); err != nil || !reflect.DeepEqual(back["server"], DefaultHTTPConfig()) {
+		t.Errorf("yaml round trip failed: %v %+v", err, back)
+	}
+}
+
+type ctxKey string
+
+func TestServerContextHooks(t *testing.T) {
+	app, base := newTestServer(t,
+		WithBaseContext(func(l net.Listener) context.Context {
+			return context.WithValue(context.Background(), ctxKey("base"), "app")
+		}),
+		WithConnContext(func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, ctxKey("conn"), c.RemoteAddr().String())
+		}),
+	)
+	app.Router().Get("/ctx", func(x *X) {
+		ctx := x.Request.Context()
+		x.WriteString(ctx.Value(ctxKey("base")).(string) + " " + ctx.Value(ctxKey("conn")).(string))
+	})
+	go app.RunContext(context.Background())
+	defer app.Shutdown(context.Background())
+
+	resp, err := http.Get(base + "/ctx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(string(b), "app 127.0.0.1:") {
+		t.Errorf("unexpected body %q", b)
+	}
+}
+
+func TestStreamIgnoresWriteTimeout(t *testing.T) {
+	app, base := newTestServer(t, WithWriteTimeout(50*time.Millisecond))
+	app.Router().Get("/sse", func(x *X) {
+		x.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			x.WriteString("data: tick\n\n")
+			x.Flush()
+			time.Sleep(40 * time.Millisecond)
+		}
+	})
+	go app.RunContext(context.Background())
+	defer app.Shutdown(context.Background())
+
+	resp, err := http.Get(base + "/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if strings.Count(string(b), "tick") != 3 {
+		t.Errorf("stream cut by write timeout: %q", b)
+	}
+}
+
+func TestLongTransfersIgnoreTimeouts(t *testing.T) {
+	app, base := newTestServer(t, WithReadTimeout(100*time.Millisecond))
+	type uploadReq struct {
+		Body io.Reader `src:"body"`
+	}
+	app.Router().Post("/upload", func(x *X) error {
+		req := &uploadReq{}
+		if err := x.Parse(req); err != nil {
+			return err
+		}
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		_, err = x.WriteString(strings.Repeat("x", len(b)))
+		return err
+	})
+	file := t.TempDir() + "/data.txt"
+	if err := os.WriteFile(file, []byte("file content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	go app.RunContext(context.Background())
+	defer app.Shutdown(context.Background())
+	wapp, wbase := newTestServer(t, WithWriteTimeout(50*time.Millisecond))
+	wapp.Router().Get("/file", func(x *X) error {
+		time.Sleep(100 * time.Millisecond)
+		return x.File(file)
+	})
+	go wapp.RunContext(context.Background())
+	defer wapp.Shutdown(context.Background())
+
+	upload := func(gaps ...time.Duration) string {
+		pr, pw := io.Pipe()
+		go func() {
+			for _, d := range gaps {
+				time.Sleep(d)
+				pw.Write([]byte("abc"))
+			}
+			pw.Close()
+		}()
+		resp, err := http.Post(base+"/upload", "application/octet-stream", pr)
+		if err != nil {
+			return err.Error()
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return string(b)
+	}
+	// 分段慢速上传, 总时长超过 ReadTimeout
+	gap := 50 * time.Millisecond
+	if b := upload(gap, gap, gap, gap); b != strings.Repeat("x", 12) {
+		t.Errorf("upload cut by read timeout: %q", b)
+	}
+	// 停止发送超过 ReadTimeout 的连接仍会超时
+	if b := upload(gap, 300*time.Millisecond, gap); b == strings.Repeat("x", 9) {
+		t.Error("stalled upload should time out")
+	}
+
+	resp, err := http.Get(wbase + "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "file content" {
+		t.Errorf("download cut by write timeout: %q", b)
+	}
+}
+
+func TestH2C(t *testing.T) {
+	app, base := newTestServer(t, WithH2C())
+	release := make(chan struct{})
+	app.Router().Get("/stream", func(x *X) {
+		x.WriteString(x.Request.Proto + "\n")
+		x.Flush()
+		<-release
+		x.WriteString("end")
+	})
+	go app.RunContext(context.Background())
+	defer app.Shutdown(context.Background())
+
+	// prior knowledge
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get(base + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "HTTP/2.0\n" || resp.ProtoMajor != 2 {
+		t.Errorf("flushed chunk should arrive before handler returns, got %q %v", line, err)
+	}
+	close(release)
+
+	// Upgrade: h2c
+	conn, err := net.Dial("tcp", strings.TrimPrefix(base, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /stream HTTP/1.1\r\nHost: vigo\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAAP__\r\n\r\n")
+	status, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.Contains(status, "101") {
+		t.Errorf("expected 101 Switching Protocols, got %q %v", status, err)
+	}
+
+	// 未开启 h2c 时不影响 HTTP/1.1
+	plain, plainBase := newTestServer(t)
+	plain.Router().Get("/proto", func(x *X) { x.WriteString(x.Request.Proto) })
+	go plain.RunContext(context.Background())
+	defer plain.Shutdown(context.Background())
+	if _, err := client.Get(plainBase + "/proto"); err == nil {
+		t.Error("h2c should be disabled by default")
+	}
+}
+
+func TestBeforeShutdownDelay(t *testing.T) {
+	app, base := newTestServer(t, WithShutdownDelay(100*time.Millisecond))
+	var ready atomic.Bool
+	ready.Store(true)
+	app.BeforeShutdown(func() { ready.Store(false) })
+	app.Router().Get("/readyz", func(x *X) {
+		if !ready.Load() {
+			x.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- app.RunContext(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+
+	// 延迟期间仍然接收请求, readiness 已不可用
+	resp, err := http.Get(base + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 during shutdown delay, got %d", resp.StatusCode)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatal(err)
+	}
+}
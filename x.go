@@ -10,7 +10,6 @@ package vigo
 import (
 	"context"
 	"fmt"
-	"net"
 	"net/http"
 	"reflect"
 	"runtime"
@@ -48,6 +47,7 @@ type X struct {
 	writer     http.ResponseWriter
 	Request    *http.Request
 	PathParams PathParams
+	route      *route
 	routeVars  map[string]any // 路由级共享变量（只读）
 	vars       map[string]any // 请求级会话变量
 	fcs        []any
@@ -109,7 +109,7 @@ func (x *X) Next() {
 				if name == "" {
 					name = runtime.FuncForPC(reflect.ValueOf(fc).Pointer()).Name()
 				}
-				logv.WithNoCaller.Warn().Msgf("unhandled error in %s: %v", name, err)
+				logv.WithNoCaller.Warn().Msgf("unhandled error in %s: %+v", name, err)
 			}
 			return
 		}
@@ -137,6 +137,11 @@ func (x *X) ResponseWriter() http.ResponseWriter {
 	return x.writer
 }
 
+// SetResponseWriter 替换响应写入器, 供缓冲/压缩等中间件包装原始 writer
+func (x *X) SetResponseWriter(w http.ResponseWriter) {
+	x.writer = w
+}
+
 func (x *X) Get(key string) any {
 	if x.vars != nil {
 		if v, ok := x.vars[key]; ok {
@@ -158,31 +163,22 @@ func (x *X) Set(key string, value any) {
 	x.vars[key] = value
 }
 
+// Route 返回匹配的路由模板, 如 /users/{id}, 未匹配时为空
+func (x *X) Route() string {
+	if x.route == nil {
+		return ""
+	}
+	return "/" + strings.TrimLeft(x.route.String(), "/")
+}
+
 func (x *X) Context() context.Context {
 	return x.Request.Context()
 }
 
+// GetRemoteIP 返回客户端 IP, 直接连接的对端属于 Config.TrustedProxies 时,
+// 从右向左查找 Forwarded/X-Forwarded-For 中第一个不可信的地址, 否则返回对端地址
 func (x *X) GetRemoteIP() string {
-	// 首先尝试从 X-Forwarded-For 获取 IP 地址
-	ip := x.Request.Header.Get("X-Forwarded-For")
-	if ip != "" {
-		// X-Forwarded-For 可能包含多个 IP 地址，以逗号分隔，
-		// 这里我们取第一个 IP 地址作为客户端的 IP。
-		return strings.TrimSpace(strings.Split(ip, ",")[0])
-	}
-
-	// 如果 X-Forwarded-For 不存在，则尝试从 X-Real-IP 获取 IP 地址
-	ip = x.Request.Header.Get("X-Real-IP")
-	if ip != "" {
-		return ip
-	}
-
-	// 如果以上两个都没有，则直接从 RemoteAddr 获取 IP 地址
-	ip, _, err := net.SplitHostPort(x.Request.RemoteAddr)
-	if err != nil {
-		return ""
-	}
-	return ip
+	return clientIP(x.Request, x.trustedProxies())
 }
 
 var xPool = sync.Pool{
@@ -208,6 +204,8 @@ func release(x *X) {
 	x.PathParams = x.PathParams[:0]
 	x.Request = nil
 	x.writer = nil
+	x.route = nil
+	x.routeVars = nil
 	// 清理请求级变量，复用 map 容量
 	for k := range x.vars {
 		delete(x.vars, k)
@@ -0,0 +1,104 @@
+//
+// xbody.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// BodyReaderKey 请求变量名, 流式请求体解析后可通过 x.Get 取回 *BodyReader
+const BodyReaderKey = "vigo.body_reader"
+
+// BodyProgressKey 路由变量名, 设置流式请求体的读取进度回调
+//
+//	router.SetVar(vigo.BodyProgressKey, func(x *vigo.X, read, total int64) {
+//		logv.Debug().Int64("read", read).Int64("total", total).Msg("upload")
+//	})
+const BodyProgressKey = "vigo.body_progress"
+
+// BodyProgress 进度回调, total 为 Content-Length, 未知时为 -1
+type BodyProgress func(x *X, read, total int64)
+
+// BodyReader 流式请求体, 统计已读取的字节数并在每次读取后回调进度
+//
+//	type ImportReq struct {
+//		Table string        `src:"query"`
+//		Body  io.ReadCloser `src:"body" mime:"text/csv"`
+//	}
+//
+// Body 字段类型可以是 io.Reader, io.ReadCloser 或 *vigo.BodyReader,
+// 含有 body 字段的结构体不会预先解析 JSON/表单, 请求体完全交由处理函数读取
+type BodyReader struct {
+	x        *X
+	rc       io.ReadCloser
+	n        int64
+	total    int64
+	progress []BodyProgress
+	// timeout 每次读取前将读超时顺延 Config.ReadTimeout, 为 0 时不限制
+	timeout time.Duration
+	ctl     *http.ResponseController
+}
+
+var bodyReaderType = reflect.TypeOf((*BodyReader)(nil))
+
+func newBodyReader(x *X) *BodyReader {
+	b := &BodyReader{x: x, rc: x.Request.Body, total: x.Request.ContentLength}
+	if b.rc == nil {
+		b.rc = http.NoBody
+	}
+	switch fn := x.Get(BodyProgressKey).(type) {
+	case BodyProgress:
+		b.progress = append(b.progress, fn)
+	case func(*X, int64, int64):
+		b.progress = append(b.progress, fn)
+	}
+	x.Set(BodyReaderKey, b)
+	// 请求体由处理函数按需读取, Config.ReadTimeout 改为限制每次读取的等待时间,
+	// 持续上传的大文件不会被中断, 停止发送的连接仍会超时
+	if s, ok := x.Request.Context().Value(http.ServerContextKey).(*http.Server); ok && s.ReadTimeout > 0 {
+		b.timeout = s.ReadTimeout
+		b.ctl = http.NewResponseController(x.writer)
+	}
+	return b
+}
+
+func (b *BodyReader) Read(p []byte) (int, error) {
+	if b.timeout > 0 {
+		_ = b.ctl.SetReadDeadline(time.Now().Add(b.timeout))
+	}
+	n, err := b.rc.Read(p)
+	if n > 0 {
+		b.n += int64(n)
+		for _, fn := range b.progress {
+			fn(b.x, b.n, b.total)
+		}
+	}
+	return n, err
+}
+
+func (b *BodyReader) Close() error {
+	return b.rc.Close()
+}
+
+// BytesRead 已读取的字节数
+func (b *BodyReader) BytesRead() int64 {
+	return b.n
+}
+
+// Total 请求体总长度, 未知时为 -1
+func (b *BodyReader) Total() int64 {
+	return b.total
+}
+
+// OnProgress 追加进度回调, 在路由级回调之后执行
+func (b *BodyReader) OnProgress(fn BodyProgress) {
+	b.progress = append(b.progress, fn)
+}
@@ -0,0 +1,87 @@
+package vigo
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBody(t *testing.T) {
+	type ImportReq struct {
+		Table string      `src:"query"`
+		Token string      `src:"header@X-Token"`
+		Body  *BodyReader `src:"body" mime:"text/csv"`
+		Title string      `json:"title"` // 流式请求时不解析 JSON
+	}
+	type PlainReq struct {
+		Body io.Reader `src:"body"`
+	}
+
+	var routerCalls, reqCalls int
+	var lastTotal int64
+	r := NewRouter()
+	r.SetVar(BodyProgressKey, func(x *X, read, total int64) {
+		routerCalls++
+		lastTotal = total
+	})
+	var got, table string
+	var read int64
+	r.Post("/import", func(x *X, req *ImportReq) (int64, error) {
+		table = req.Table + "/" + req.Token
+		req.Body.OnProgress(func(x *X, read, total int64) { reqCalls++ })
+		data, err := io.ReadAll(req.Body)
+		got = string(data)
+		if br, _ := x.Get(BodyReaderKey).(*BodyReader); br != req.Body {
+			t.Error("expected body reader stored on X")
+		}
+		read = req.Body.BytesRead()
+		return read, err
+	})
+	r.Post("/plain", func(x *X, req *PlainReq) error {
+		data, err := io.ReadAll(req.Body)
+		got = string(data)
+		return err
+	})
+
+	body := "a,b\n1,2\n"
+	req := httptest.NewRequest(http.MethodPost, "/import?Table=users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Token", "t")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 200 || got != body || table != "users/t" {
+		t.Fatalf("unexpected result: code=%d body=%q table=%q resp=%s", w.Code, got, table, w.Body.String())
+	}
+	if read != int64(len(body)) {
+		t.Errorf("expected byte count %d, got %d", len(body), read)
+	}
+	if routerCalls == 0 || reqCalls == 0 || lastTotal != int64(len(body)) {
+		t.Errorf("progress hooks not called: router=%d req=%d total=%d", routerCalls, reqCalls, lastTotal)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/plain", strings.NewReader("raw")))
+	if got != "raw" {
+		t.Errorf("expected raw body, got %q", got)
+	}
+
+	params, docBody := parseDocArgs(reflect.TypeOf(ImportReq{}))
+	if len(params) != 2 || docBody == nil || docBody.Type != "binary" || docBody.ContentType != "text/csv" {
+		t.Errorf("unexpected doc: params=%d body=%+v", len(params), docBody)
+	}
+}
+
+func TestParseBody_InvalidField(t *testing.T) {
+	type BadReq struct {
+		Body string `src:"body"`
+	}
+	x, _ := createTestX("POST", "/", nil)
+	defer release(x)
+	if err := x.Parse(&BadReq{}); err == nil || !strings.Contains(err.Error(), "body field") {
+		t.Errorf("expected body field type error, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+//
+// xenvelope.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// EnvelopeKey 路由变量名, 设置统一响应包装, 由 common.JsonResponse/JsonErrorResponse 使用
+//
+//	router.SetVar(vigo.EnvelopeKey, &vigo.DefaultEnvelope{})
+//	router.Get("/raw", handler).SetVar(vigo.EnvelopeKey, nil) // 单个路由不包装
+const EnvelopeKey = "vigo.envelope"
+
+// Envelope 统一响应包装
+type Envelope interface {
+	// Wrap 包装响应, err 非空时为错误响应, 返回状态码和响应体
+	Wrap(x *X, data any, err error) (int, any)
+	// Schema 返回文档中的响应结构, data 为路由返回类型
+	Schema(data reflect.Type) reflect.Type
+}
+
+// Envelope 返回当前请求的响应包装, 未设置时为 nil
+func (x *X) Envelope() Envelope {
+	e, _ := x.Get(EnvelopeKey).(Envelope)
+	return e
+}
+
+// EnvelopeBody 默认包装结构
+type EnvelopeBody struct {
+	Code      int    `json:"code"`
+	Data      any    `json:"data,omitempty"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	// Details/Fields 仅错误响应时存在
+	Details map[string]any    `json:"details,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// DefaultEnvelope 包装为 {code, data, message, request_id}
+type DefaultEnvelope struct {
+	// SuccessCode 成功响应的 code, 默认 0
+	SuccessCode int
+	// SuccessMessage 成功响应的 message
+	SuccessMessage string
+	// RequestID 获取请求 ID, 默认取响应头或请求头中的 X-Request-Id
+	RequestID func(x *X) string
+}
+
+var _ Envelope = &DefaultEnvelope{}
+
+func (e *DefaultEnvelope) Wrap(x *X, data any, err error) (int, any) {
+	body := &EnvelopeBody{Code: e.SuccessCode, Data: data, Message: e.SuccessMessage}
+	if e.RequestID != nil {
+		body.RequestID = e.RequestID(x)
+	} else if id := x.Header().Get("X-Request-Id"); id != "" {
+		body.RequestID = id
+	} else {
+		body.RequestID = x.Request.Header.Get("X-Request-Id")
+	}
+	if err == nil {
+		return http.StatusOK, body
+	}
+	body.Data = nil
+	var ve *Error
+	if errors.As(err, &ve) {
+		body.Code, body.Message = ve.Code, x.ErrorMessage(ve)
+		body.Details, body.Fields = ve.Details, ve.Fields
+		return ve.Status(), body
+	}
+	body.Code, body.Message = http.StatusBadRequest, err.Error()
+	return http.StatusBadRequest, body
+}
+
+func (e *DefaultEnvelope) Schema(data reflect.Type) reflect.Type {
+	fields := []reflect.StructField{
+		{Name: "Code", Type: reflect.TypeOf(0), Tag: `json:"code"`},
+	}
+	if data != nil {
+		fields = append(fields, reflect.StructField{Name: "Data", Type: data, Tag: `json:"data"`})
+	}
+	return reflect.StructOf(append(fields,
+		reflect.StructField{Name: "Message", Type: reflect.TypeOf(""), Tag: `json:"message"`},
+		reflect.StructField{Name: "RequestID", Type: reflect.TypeOf(""), Tag: `json:"request_id,omitempty" desc:"X-Request-Id"`},
+	))
+}
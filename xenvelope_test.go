@@ -0,0 +1,84 @@
+package vigo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorStatus(t *testing.T) {
+	cases := map[int]int{40001: 400, 40400: 404, 50300: 503, 404: 404, 4291: 429, 1: 400}
+	for code, want := range cases {
+		if got := (&Error{Code: code}).Status(); got != want {
+			t.Errorf("Status(%d) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	r := NewRouter()
+	r.SetVar(EnvelopeKey, &DefaultEnvelope{})
+	r.After(func(x *X, data any) error {
+		if env := x.Envelope(); env != nil {
+			status, body := env.Wrap(x, data, nil)
+			x.WriteHeader(status)
+			return x.JSON(body)
+		}
+		return x.JSON(data)
+	}, func(x *X, err error) error {
+		status, body := x.Envelope().Wrap(x, nil, err)
+		x.WriteHeader(status)
+		return x.JSON(body)
+	})
+	r.Get("/user", "get user", func(x *X) (*DocTestUser, error) {
+		return &DocTestUser{Name: "a"}, nil
+	})
+	r.Get("/missing", func(x *X) (any, error) { return nil, ErrNotFound })
+	r.Get("/plain", func(x *X) (any, error) { return nil, errors.New("boom") })
+	r.Get("/raw", "raw", func(x *X) (string, error) { return "raw", nil }).SetVar(EnvelopeKey, nil)
+
+	do := func(path string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Request-Id", "rid")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var m map[string]any
+		json.Unmarshal(w.Body.Bytes(), &m)
+		return w, m
+	}
+
+	w, m := do("/user")
+	if w.Code != 200 || m["code"] != 0.0 || m["request_id"] != "rid" || m["data"].(map[string]any)["name"] != "a" {
+		t.Errorf("unexpected success envelope %d %s", w.Code, w.Body.String())
+	}
+	w, m = do("/missing")
+	if w.Code != 404 || m["code"] != 40400.0 || m["message"] != "not found" || m["data"] != nil {
+		t.Errorf("unexpected error envelope %d %s", w.Code, w.Body.String())
+	}
+	if w, m := do("/plain"); w.Code != 400 || m["message"] != "boom" {
+		t.Errorf("unexpected plain error envelope %d %s", w.Code, w.Body.String())
+	}
+	if w, _ := do("/raw"); w.Body.String() != "raw" {
+		t.Errorf("expected raw response, got %q", w.Body.String())
+	}
+
+	doc := r.Doc()
+	for _, route := range doc.Routes {
+		switch route.Path {
+		case "/user":
+			names := []string{}
+			for _, f := range route.Response.Fields {
+				names = append(names, f.Name)
+			}
+			if len(names) != 4 || names[0] != "code" || names[1] != "data" || route.Response.Fields[1].Type != "object" {
+				t.Errorf("expected enveloped doc response, got %v", names)
+			}
+		case "/raw":
+			if route.Response.Type != "string" {
+				t.Errorf("expected raw doc response, got %s", route.Response.Type)
+			}
+		}
+	}
+}
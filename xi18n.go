@@ -0,0 +1,360 @@
+//
+// xi18n.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// I18nKey 路由变量名, 设置 x.T 使用的翻译表
+	I18nKey = "vigo.i18n"
+	// LangKey 请求变量名, 保存协商出的语言, 可在中间件中提前设置以覆盖 Accept-Language
+	LangKey = "vigo.lang"
+)
+
+var pluralForms = []string{"zero", "one", "two", "few", "many", "other"}
+
+type i18nMessage struct {
+	text   string
+	plural map[string]string
+}
+
+// I18n 多语言翻译表
+//
+//	i18n := vigo.NewI18n("en")
+//	i18n.LoadFS(localesFS, "locales/*.yaml") // 文件名即语言, 如 locales/zh-CN.yaml
+//	router.SetVar(vigo.I18nKey, i18n)
+//	x.T("hello", "name", "vigo")             // hello: "你好, {name}"
+//	x.T("items", "count", 3)                 // items: {one: "{count} item", other: "{count} items"}
+//
+// 嵌套的 key 以 . 连接, 如 user.login.failed; errors.<code> 用于翻译 vigo.Error.
+// 找不到翻译时依次尝试回退语言和 key 本身
+type I18n struct {
+	fallback string
+	mu       sync.RWMutex
+	langs    map[string]map[string]*i18nMessage // 小写语言 -> key -> 翻译
+	tags     []string                           // 加载顺序的原始语言标签
+}
+
+// NewI18n 创建翻译表, fallback 为回退语言
+func NewI18n(fallback string) *I18n {
+	return &I18n{
+		fallback: strings.ToLower(fallback),
+		langs:    make(map[string]map[string]*i18nMessage),
+	}
+}
+
+// LoadFS 加载 fsys 中匹配 patterns 的 yaml/json 文件, 文件名 (不含扩展名) 为语言标签
+func (i *I18n) LoadFS(fsys fs.FS, patterns ...string) error {
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		for _, name := range matches {
+			b, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return err
+			}
+			var data map[string]any
+			switch ext := path.Ext(name); ext {
+			case ".json":
+				err = json.Unmarshal(b, &data)
+			case ".yaml", ".yml":
+				err = yaml.Unmarshal(b, &data)
+			default:
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("i18n %s: %w", name, err)
+			}
+			i.Add(strings.TrimSuffix(path.Base(name), path.Ext(name)), data)
+		}
+	}
+	return nil
+}
+
+// Add 添加一种语言的翻译, 与已有翻译合并
+func (i *I18n) Add(lang string, messages map[string]any) *I18n {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	key := strings.ToLower(lang)
+	table := i.langs[key]
+	if table == nil {
+		table = make(map[string]*i18nMessage)
+		i.langs[key] = table
+		i.tags = append(i.tags, lang)
+	}
+	flattenMessages(table, "", messages)
+	return i
+}
+
+func flattenMessages(table map[string]*i18nMessage, prefix string, messages map[string]any) {
+	for k, v := range messages {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			if plural, ok := pluralMessage(v); ok {
+				table[key] = &i18nMessage{plural: plural}
+			} else {
+				flattenMessages(table, key, v)
+			}
+		default:
+			table[key] = &i18nMessage{text: fmt.Sprint(v)}
+		}
+	}
+}
+
+// pluralMessage 键全部为复数形式且包含 other 时视为复数翻译
+func pluralMessage(m map[string]any) (map[string]string, bool) {
+	if _, ok := m["other"]; !ok {
+		return nil, false
+	}
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		if !slices.Contains(pluralForms, k) {
+			return nil, false
+		}
+		res[k] = fmt.Sprint(v)
+	}
+	return res, true
+}
+
+// Languages 返回已加载的语言
+func (i *I18n) Languages() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]string(nil), i.tags...)
+}
+
+// Match 按 Accept-Language 选择语言, 依次匹配完整标签和主语言, 都不匹配时返回回退语言
+func (i *I18n) Match(acceptLanguage string) string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		if _, ok := i.langs[tag]; ok {
+			return tag
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		if _, ok := i.langs[base]; ok {
+			return base
+		}
+		// zh 匹配 zh-cn
+		for _, t := range i.tags {
+			if l := strings.ToLower(t); strings.HasPrefix(l, base+"-") {
+				return l
+			}
+		}
+	}
+	return i.fallback
+}
+
+// parseAcceptLanguage 返回按 q 值降序排列的小写语言标签
+func parseAcceptLanguage(header string) []string {
+	type item struct {
+		tag string
+		q   float64
+	}
+	var items []item
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			items = append(items, item{tag, q})
+		}
+	}
+	sort.SliceStable(items, func(a, b int) bool { return items[a].q > items[b].q })
+	res := make([]string, len(items))
+	for idx, it := range items {
+		res[idx] = it.tag
+	}
+	return res
+}
+
+// Lookup 查找翻译, 找不到时尝试回退语言
+func (i *I18n) Lookup(lang, key string, args ...any) (string, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	lang = strings.ToLower(lang)
+	msg := i.langs[lang][key]
+	if msg == nil {
+		lang, msg = i.fallback, i.langs[i.fallback][key]
+	}
+	if msg == nil {
+		return "", false
+	}
+	params := i18nParams(args)
+	text := msg.text
+	if msg.plural != nil {
+		text = msg.plural["other"]
+		if count, ok := toInt(params["count"]); ok {
+			if s, ok := msg.plural["zero"]; ok && count == 0 {
+				// 显式的 zero 对所有语言生效, 如 "no items"
+				text = s
+			} else if s, ok := msg.plural[pluralForm(lang, int(count))]; ok {
+				text = s
+			}
+		}
+	}
+	return interpolate(text, params), true
+}
+
+// pluralForm 按 CLDR 规则返回 count 对应的复数形式, 如 en 只区分 one/other, ru 区分 one/few/many/other
+func pluralForm(lang string, count int) string {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return "other"
+	}
+	if count < 0 {
+		count = -count
+	}
+	switch plural.Cardinal.MatchPlural(tag, count, 0, 0, 0, 0) {
+	case plural.Zero:
+		return "zero"
+	case plural.One:
+		return "one"
+	case plural.Two:
+		return "two"
+	case plural.Few:
+		return "few"
+	case plural.Many:
+		return "many"
+	}
+	return "other"
+}
+
+// T 翻译 key, 找不到时返回插值后的 key
+func (i *I18n) T(lang, key string, args ...any) string {
+	if s, ok := i.Lookup(lang, key, args...); ok {
+		return s
+	}
+	return interpolate(key, i18nParams(args))
+}
+
+// Apply 作为中间件为当前请求设置翻译表
+func (i *I18n) Apply(x *X) {
+	x.Set(I18nKey, i)
+}
+
+// i18nParams 参数为 map[string]any 或 key, value 交替
+func i18nParams(args []any) map[string]any {
+	if len(args) == 1 {
+		if m, ok := args[0].(map[string]any); ok {
+			return m
+		}
+	}
+	params := make(map[string]any, len(args)/2)
+	for idx := 0; idx+1 < len(args); idx += 2 {
+		params[fmt.Sprint(args[idx])] = args[idx+1]
+	}
+	return params
+}
+
+func interpolate(text string, params map[string]any) string {
+	if len(params) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func toInt(v any) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return rv.Int(), true
+	case rv.CanUint():
+		return int64(rv.Uint()), true
+	case rv.CanFloat():
+		f := rv.Float()
+		return int64(f), f == float64(int64(f))
+	}
+	return 0, false
+}
+
+func (x *X) i18n() *I18n {
+	i, _ := x.Get(I18nKey).(*I18n)
+	return i
+}
+
+// Lang 返回当前请求的语言, 首次调用时按 Accept-Language 协商并保存在 LangKey
+func (x *X) Lang() string {
+	if lang, ok := x.Get(LangKey).(string); ok && lang != "" {
+		return lang
+	}
+	i := x.i18n()
+	if i == nil {
+		return ""
+	}
+	lang := i.Match(x.Request.Header.Get("Accept-Language"))
+	addVary(x.Header(), "Accept-Language")
+	x.Set(LangKey, lang)
+	return lang
+}
+
+// T 按当前请求的语言翻译, args 为 key, value 交替或 map[string]any, count 参数用于选择复数形式
+func (x *X) T(key string, args ...any) string {
+	i := x.i18n()
+	if i == nil {
+		return interpolate(key, i18nParams(args))
+	}
+	return i.T(x.Lang(), key, args...)
+}
+
+// ErrorMessage 返回当前语言的错误信息, 按 errors.<code> 翻译, Details 可用于插值
+//
+// WithArgs 等追加的内容保留在翻译之后; WithMessage 替换过的信息不翻译
+func (x *X) ErrorMessage(e *Error) string {
+	i := x.i18n()
+	if i == nil {
+		return e.Message
+	}
+	// 只翻译 Register 登记过的错误
+	info, ok := LookupError(e.Code)
+	if !ok || !strings.HasPrefix(e.Message, info.Message) {
+		return e.Message
+	}
+	suffix := e.Message[len(info.Message):]
+	s, ok := i.Lookup(x.Lang(), "errors."+strconv.Itoa(e.Code), e.Details)
+	if !ok {
+		return e.Message
+	}
+	return s + suffix
+}
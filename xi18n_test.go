@@ -0,0 +1,148 @@
+package vigo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestI18n(t *testing.T) *I18n {
+	fsys := fstest.MapFS{
+		"locales/en.yaml": {Data: []byte(`
+hello: "Hello, {name}"
+items:
+  zero: no items
+  one: "{count} item"
+  other: "{count} items"
+user:
+  login:
+    failed: login failed
+errors:
+  "40401": "resource not found"
+`)},
+		"locales/zh-CN.json": {Data: []byte(`{
+  "hello": "你好, {name}",
+  "items": {"other": "{count} 个项目"},
+  "errors": {"40401": "资源不存在", "40102": "登录已过期 ({minutes} 分钟)"}
+}`)},
+		"locales/ru.yaml": {Data: []byte(`
+files:
+  one: "{count} файл"
+  few: "{count} файла"
+  many: "{count} файлов"
+  other: "{count} файла"
+`)},
+		"locales/readme.txt": {Data: []byte("ignored")},
+	}
+	i := NewI18n("en")
+	if err := i.LoadFS(fsys, "locales/*"); err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func TestI18nMatch(t *testing.T) {
+	i := newTestI18n(t)
+	cases := map[string]string{
+		"":                          "en",
+		"zh-CN,zh;q=0.9,en;q=0.8":   "zh-cn",
+		"zh":                        "zh-cn",
+		"zh-TW":                     "zh-cn",
+		"fr, en;q=0.5":              "en",
+		"en;q=0.2, zh-cn;q=0.8":     "zh-cn",
+		"de":                        "en",
+		"zh-CN;q=0, en-US;q=0.5, *": "en",
+	}
+	for header, want := range cases {
+		if got := i.Match(header); got != want {
+			t.Errorf("Match(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestI18nT(t *testing.T) {
+	i := newTestI18n(t)
+	cases := []struct {
+		lang, key string
+		args      []any
+		want      string
+	}{
+		{"en", "hello", []any{"name", "vigo"}, "Hello, vigo"},
+		{"zh-cn", "hello", []any{map[string]any{"name": "vigo"}}, "你好, vigo"},
+		{"en", "items", []any{"count", 0}, "no items"},
+		{"en", "items", []any{"count", 1}, "1 item"},
+		{"en", "items", []any{"count", 5}, "5 items"},
+		{"en", "items", []any{"count", 2}, "2 items"},
+		{"zh-cn", "items", []any{"count", 1}, "1 个项目"},
+		{"ru", "files", []any{"count", 1}, "1 файл"},
+		{"ru", "files", []any{"count", 21}, "21 файл"},
+		{"ru", "files", []any{"count", 3}, "3 файла"},
+		{"ru", "files", []any{"count", 5}, "5 файлов"},
+		{"ru", "files", []any{"count", 11}, "11 файлов"},
+		{"en", "user.login.failed", nil, "login failed"},
+		{"zh-cn", "user.login.failed", nil, "login failed"}, // 回退语言
+		{"en", "missing {name}", []any{"name", "x"}, "missing x"},
+	}
+	for _, c := range cases {
+		if got := i.T(c.lang, c.key, c.args...); got != c.want {
+			t.Errorf("T(%s, %s) = %q, want %q", c.lang, c.key, got, c.want)
+		}
+	}
+}
+
+func TestI18nX(t *testing.T) {
+	i := newTestI18n(t)
+	r := NewRouter()
+	r.After(func(x *X, err error) error {
+		status, body := x.Envelope().Wrap(x, nil, err)
+		x.WriteHeader(status)
+		return x.JSON(body)
+	})
+	r.Get("/hello", func(x *X) error {
+		_, err := x.WriteString(x.Lang() + ":" + x.T("hello", "name", "vigo"))
+		return err
+	})
+	r.Get("/err/{kind}", func(x *X) error {
+		switch x.PathParams.Get("kind") {
+		case "args":
+			return ErrResourceNotFound.WithArgs("user 7")
+		case "details":
+			return ErrTokenExpired.WithDetail("minutes", 30)
+		case "custom":
+			return ErrResourceNotFound.WithMessage("gone")
+		}
+		return NewError("plain")
+	})
+	r.SetVar(I18nKey, i)
+	r.SetVar(EnvelopeKey, &DefaultEnvelope{})
+
+	do := func(path, lang string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := do("/hello", "zh-CN"); w.Body.String() != "zh-cn:你好, vigo" || w.Header().Get("Vary") != "Accept-Language" {
+		t.Errorf("unexpected response %q %v", w.Body.String(), w.Header())
+	}
+	if w := do("/hello", "fr"); w.Body.String() != "en:Hello, vigo" {
+		t.Errorf("unexpected fallback %q", w.Body.String())
+	}
+	cases := []struct{ path, lang, want string }{
+		{"/err/args", "zh-CN", "资源不存在: user 7"},
+		{"/err/args", "en", "resource not found: user 7"},
+		{"/err/details", "zh", "登录已过期 (30 分钟)"},
+		{"/err/details", "en", "token expired"},
+		{"/err/custom", "zh", "gone"},
+		{"/err/plain", "zh", "plain"},
+	}
+	for _, c := range cases {
+		w := do(c.path, c.lang)
+		if !strings.Contains(w.Body.String(), `"message":"`+c.want+`"`) {
+			t.Errorf("%s [%s]: expected %q, got %d %s", c.path, c.lang, c.want, w.Code, w.Body.String())
+		}
+	}
+}
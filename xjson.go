@@ -0,0 +1,229 @@
+//
+// xjson.go
+// Copyright (C) 2025 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSONPolicyKey 路由变量名, 用于设置 JSON 请求体的解码策略
+//
+//	router.SetVar(vigo.JSONPolicyKey, &vigo.JSONPolicy{MaxBytes: 1 << 20})
+//	router.Post("/import", policy.Apply, handler) // 单个路由
+const JSONPolicyKey = "vigo.json_policy"
+
+// JSONPolicy JSON 请求体解码策略, 未设置时保持宽松解码
+type JSONPolicy struct {
+	// MaxBytes 请求体最大字节数, 超出返回 413
+	MaxBytes int64
+	// DisallowUnknownFields 拒绝目标结构体中不存在的字段
+	DisallowUnknownFields bool
+	// MaxDepth 对象/数组最大嵌套层数
+	MaxDepth int
+	// UseNumber 将 any 类型中的数字解析为 json.Number, 避免大整数丢失精度
+	UseNumber bool
+}
+
+// Apply 作为中间件为当前请求设置解码策略
+func (p *JSONPolicy) Apply(x *X) {
+	x.Set(JSONPolicyKey, p)
+}
+
+func (p *JSONPolicy) strict() bool {
+	return p.DisallowUnknownFields || p.MaxDepth > 0
+}
+
+// decodeJSON 按策略解码请求体, 空请求体不视为错误
+func (x *X) decodeJSON(target any) error {
+	policy, _ := x.Get(JSONPolicyKey).(*JSONPolicy)
+	if policy == nil {
+		err := json.NewDecoder(x.Request.Body).Decode(target)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return ErrInvalidArg.WithArgs(err)
+		}
+		return nil
+	}
+
+	var body io.Reader = x.Request.Body
+	if policy.MaxBytes > 0 {
+		body = http.MaxBytesReader(x.writer, x.Request.Body, policy.MaxBytes)
+	}
+
+	if policy.strict() {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return jsonDecodeErr(err)
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil
+		}
+		var raw any
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return jsonDecodeErr(err)
+		}
+		if err := validateJSON(raw, reflect.TypeOf(target), "$", 1, policy); err != nil {
+			return ErrInvalidArg.WithArgs(err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	dec := json.NewDecoder(body)
+	if policy.UseNumber {
+		dec.UseNumber()
+	}
+	if policy.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	err := dec.Decode(target)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return jsonDecodeErr(err)
+}
+
+// jsonDecodeErr 转换解码错误, 尽量带上出错的 JSON 路径
+func jsonDecodeErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return ErrPayloadTooLarge.WithArgs(fmt.Sprintf("limit %d bytes", maxErr.Limit))
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		path := "$"
+		for _, seg := range strings.Split(typeErr.Field, ".") {
+			if seg == "" {
+				continue
+			}
+			if _, err := strconv.Atoi(seg); err == nil {
+				path += "[" + seg + "]"
+			} else {
+				path += "." + seg
+			}
+		}
+		return ErrInvalidArg.WithArgs(fmt.Sprintf("%s: expected %s, got %s", path, typeErr.Type, typeErr.Value))
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return ErrInvalidArg.WithArgs(fmt.Sprintf("offset %d: %s", syntaxErr.Offset, syntaxErr))
+	}
+	return ErrInvalidArg.WithArgs(err)
+}
+
+// validateJSON 检查嵌套层数和未知字段, t 为 nil 时只检查层数
+func validateJSON(v any, t reflect.Type, path string, depth int, p *JSONPolicy) error {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && (t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(jsonUnmarshalerType)) {
+		t = nil
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if p.MaxDepth > 0 && depth > p.MaxDepth {
+			return fmt.Errorf("%s: exceeds max depth %d", path, p.MaxDepth)
+		}
+		for key, item := range v {
+			var itemType reflect.Type
+			if t != nil {
+				switch t.Kind() {
+				case reflect.Struct:
+					ft, ok := getJSONFields(t)[strings.ToLower(key)]
+					if !ok && p.DisallowUnknownFields {
+						return fmt.Errorf("%s.%s: unknown field", path, key)
+					}
+					itemType = ft
+				case reflect.Map:
+					itemType = t.Elem()
+				}
+			}
+			if err := validateJSON(item, itemType, path+"."+key, depth+1, p); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if p.MaxDepth > 0 && depth > p.MaxDepth {
+			return fmt.Errorf("%s: exceeds max depth %d", path, p.MaxDepth)
+		}
+		var itemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			itemType = t.Elem()
+		}
+		for i, item := range v {
+			if err := validateJSON(item, itemType, fmt.Sprintf("%s[%d]", path, i), depth+1, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var jsonFieldsCache sync.Map // map[reflect.Type]map[string]reflect.Type
+
+// getJSONFields 返回结构体可被 JSON 解码的字段, key 为小写字段名
+// 与 encoding/json 一致: 大小写不敏感, 展开匿名结构体
+func getJSONFields(t reflect.Type) map[string]reflect.Type {
+	if v, ok := jsonFieldsCache.Load(t); ok {
+		return v.(map[string]reflect.Type)
+	}
+	fields := make(map[string]reflect.Type)
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		// 外层字段优先于匿名结构体中的同名字段
+		var embedded []reflect.Type
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if field.Anonymous && name == "" {
+				ft := field.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					embedded = append(embedded, ft)
+					continue
+				}
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			key := strings.ToLower(name)
+			if _, ok := fields[key]; !ok {
+				fields[key] = field.Type
+			}
+		}
+		for _, ft := range embedded {
+			collect(ft)
+		}
+	}
+	collect(t)
+	v, _ := jsonFieldsCache.LoadOrStore(t, fields)
+	return v.(map[string]reflect.Type)
+}
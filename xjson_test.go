@@ -0,0 +1,95 @@
+package vigo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newJSONRequest(target, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestJSONPolicy(t *testing.T) {
+	type Item struct {
+		Name string `json:"name"`
+	}
+	type Req struct {
+		Title string         `json:"title"`
+		Items []Item         `json:"items"`
+		Extra map[string]any `json:"extra"`
+	}
+
+	tests := []struct {
+		name    string
+		policy  *JSONPolicy
+		body    string
+		code    int
+		errPart string
+	}{
+		{"no policy", nil, `{"title":"a","unknown":1}`, 0, ""},
+		{"max bytes ok", &JSONPolicy{MaxBytes: 64}, `{"title":"a"}`, 0, ""},
+		{"max bytes exceeded", &JSONPolicy{MaxBytes: 8}, `{"title":"aaaaaaaaaa"}`, 41300, "limit 8 bytes"},
+		{"unknown top", &JSONPolicy{DisallowUnknownFields: true}, `{"title":"a","unknown":1}`, 40001, "$.unknown: unknown field"},
+		{"unknown nested", &JSONPolicy{DisallowUnknownFields: true}, `{"items":[{"name":"a"},{"nam":"b"}]}`, 40001, "$.items[1].nam: unknown field"},
+		{"case insensitive", &JSONPolicy{DisallowUnknownFields: true}, `{"TITLE":"a","extra":{"x":{"y":1}}}`, 0, ""},
+		{"depth ok", &JSONPolicy{MaxDepth: 3}, `{"items":[{"name":"a"}]}`, 0, ""},
+		{"depth exceeded", &JSONPolicy{MaxDepth: 2}, `{"extra":{"a":{"b":1}}}`, 40001, "$.extra.a: exceeds max depth 2"},
+		{"type error path", &JSONPolicy{UseNumber: true}, `{"items":[{"name":1}]}`, 40001, "$.items[0].name: expected string"},
+		{"empty body", &JSONPolicy{DisallowUnknownFields: true}, ``, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := acquire()
+			defer release(x)
+			x.Request = newJSONRequest("/", tt.body)
+			x.writer = httptest.NewRecorder()
+			if tt.policy != nil {
+				tt.policy.Apply(x)
+			}
+
+			var target Req
+			err := x.Parse(&target)
+			if tt.code == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var e *Error
+			if !errors.As(err, &e) || e.Code != tt.code {
+				t.Fatalf("expected code %d, got %v", tt.code, err)
+			}
+			if !strings.Contains(e.Message, tt.errPart) {
+				t.Errorf("expected error to contain %q, got %q", tt.errPart, e.Message)
+			}
+		})
+	}
+}
+
+func TestJSONPolicy_UseNumberAndRouterVar(t *testing.T) {
+	type Req struct {
+		Data map[string]any `json:"data"`
+	}
+	r := NewRouter()
+	r.SetVar(JSONPolicyKey, &JSONPolicy{UseNumber: true})
+	var got any
+	r.Post("/num", func(x *X, req *Req) error {
+		got = req.Data["id"]
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newJSONRequest("/num", `{"data":{"id":9007199254740993}}`))
+
+	n, ok := got.(json.Number)
+	if !ok || n.String() != "9007199254740993" {
+		t.Errorf("expected json.Number 9007199254740993, got %#v", got)
+	}
+}
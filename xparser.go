@@ -8,11 +8,11 @@
 package vigo
 
 import (
+	"encoding"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -31,6 +31,7 @@ const (
 	sourceQuery
 	sourceHeader
 	sourcePath
+	sourceBody
 )
 
 type fieldInfo struct {
@@ -38,12 +39,16 @@ type fieldInfo struct {
 	Name       string      // key name in source
 	Source     parseSource // parse source
 	DefaultVal *string     // default value if present
+	Format     string      // time layout from format tag
+	Style      string      // query array/object style
 	Field      reflect.StructField
 	IsFile     bool
 }
 
 type typeInfo struct {
 	Fields []fieldInfo
+	// HasBody 含有 src:"body" 字段, 请求体不做预解析
+	HasBody bool
 }
 
 var typeCache sync.Map // map[reflect.Type]*typeInfo
@@ -106,6 +111,8 @@ func getOrCreateTypeInfo(rt reflect.Type) *typeInfo {
 			Index:      i,
 			Name:       fieldName,
 			DefaultVal: defaultTag,
+			Format:     field.Tag.Get("format"),
+			Style:      getFieldStyle(field),
 			Field:      field,
 			IsFile:     isFileType(field.Type),
 		}
@@ -121,6 +128,9 @@ func getOrCreateTypeInfo(rt reflect.Type) *typeInfo {
 			fInfo.Source = sourceHeader
 		case strings.HasPrefix(parseTag, "path"):
 			fInfo.Source = sourcePath
+		case parseTag == "body":
+			fInfo.Source = sourceBody
+			info.HasBody = true
 		default:
 			fInfo.Source = sourceJSON
 		}
@@ -132,132 +142,237 @@ func getOrCreateTypeInfo(rt reflect.Type) *typeInfo {
 	return v.(*typeInfo)
 }
 
+// VigoParser 由 vigogen 为请求结构体生成, X.Parse 检测到后不再走反射解析
+//
+//	//go:generate go run github.com/veypi/vigo/cmd/vigogen -type=UserReq
+type VigoParser interface {
+	ParseVigo(x *X) error
+}
+
 // Parse 从 HTTP 请求中解析参数到目标结构体
 // 从不同来源解析目标结构体一级字段
 // tag标签 src:"path/header/query/form/json" 可以追加为 path@alias_name
+// tag标签 src:"body" 将请求体以 io.Reader 形式交给处理函数, 见 BodyReader
 // tag标签 default:""
+// tag标签 format:"2006-01-02" 指定 time.Time 的解析格式, 支持 unix/unixmilli
+// tag标签 style:"form/comma/pipe/space/deepObject" 指定 query 数组/对象的格式
+// 目标实现了 VigoParser 时直接调用生成的 ParseVigo
 func (x *X) Parse(target any) error {
-	parsedJSON := false
-	parseJSON := func() error {
-		if parsedJSON {
-			return nil
-		}
-		parsedJSON = true
-
-		if x.Request.Body == nil {
-			return nil
-		}
-
-		// Reset body if needed? No, usually body can only be read once.
-		// Assuming this function is called only once or handled correctly.
-		err := json.NewDecoder(x.Request.Body).Decode(target)
-		if errors.Is(err, io.EOF) {
-			// Empty body is not an error
-			return nil
-		} else if err != nil {
-			return ErrInvalidArg.WithArgs(err)
-		}
-		return nil
+	if p, ok := target.(VigoParser); ok {
+		return p.ParseVigo(x)
 	}
+	return x.parseReflect(target)
+}
 
+// parseReflect 反射解析, 不检查 VigoParser
+func (x *X) parseReflect(target any) error {
 	rv := reflect.ValueOf(target)
 	if rv.Kind() != reflect.Ptr {
 		return fmt.Errorf("target must be a pointer to struct: %s", rv.Kind())
 	}
 	if rv.Elem().Kind() != reflect.Struct {
-		return parseJSON()
+		c := &ParseContext{x: x, target: target}
+		return c.JSON()
+	}
+
+	c, err := x.NewParseContext(target)
+	if err != nil {
+		return err
+	}
+	c.rv = rv.Elem()
+	c.info = getOrCreateTypeInfo(c.rv.Type())
+
+	for i := range c.info.Fields {
+		if err := c.parseField(&c.info.Fields[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseContext 单次解析的上下文, 供 X.Parse 和 vigogen 生成的代码共用
+type ParseContext struct {
+	x          *X
+	target     any
+	parsedJSON bool
+	streaming  bool
+	query      url.Values
+
+	// 反射解析时使用, 生成代码按需初始化
+	rv   reflect.Value
+	info *typeInfo
+}
+
+// NewParseContext 根据 Content-Type 预先解析表单和 JSON 请求体
+func (x *X) NewParseContext(target any) (*ParseContext, error) {
+	c := &ParseContext{x: x, target: target}
+
+	// 流式请求体由处理函数自行读取
+	if rt := reflect.TypeOf(target); rt != nil && rt.Kind() == reflect.Ptr && rt.Elem().Kind() == reflect.Struct {
+		if getOrCreateTypeInfo(rt.Elem()).HasBody {
+			c.streaming = true
+			return c, nil
+		}
 	}
 
 	// 检查是否需要解析 multipart form（用于文件上传）
 	contentType := x.Request.Header.Get("Content-Type")
 	if strings.Contains(contentType, "multipart/form-data") {
 		if err := x.Request.ParseMultipartForm(32 << 20); err != nil { // 32MB max
-			return fmt.Errorf("failed to parse multipart form: %w", err)
+			return nil, fmt.Errorf("failed to parse multipart form: %w", err)
 		}
 	} else if strings.Contains(contentType, "application/x-www-form-urlencoded") {
 		if err := x.Request.ParseForm(); err != nil {
-			return fmt.Errorf("failed to parse form: %w", err)
+			return nil, fmt.Errorf("failed to parse form: %w", err)
 		}
 	}
 
 	// 解析 JSON 数据
 	if strings.Contains(contentType, "application/json") {
-		if err := parseJSON(); err != nil {
-			return err
+		if err := c.JSON(); err != nil {
+			return nil, err
 		}
 	}
+	return c, nil
+}
 
-	rv = rv.Elem()
-	rt := rv.Type()
+// JSON 将请求体解码到目标, 只执行一次
+func (c *ParseContext) JSON() error {
+	if c.parsedJSON || c.streaming {
+		return nil
+	}
+	c.parsedJSON = true
 
-	// Use Cached TypeInfo
-	info := getOrCreateTypeInfo(rt)
+	if c.x.Request.Body == nil {
+		return nil
+	}
 
-	for _, fieldInfo := range info.Fields {
-		fieldValue := rv.Field(fieldInfo.Index)
-		if !fieldValue.CanSet() {
-			continue
+	// Reset body if needed? No, usually body can only be read once.
+	// Assuming this function is called only once or handled correctly.
+	return c.x.decodeJSON(c.target)
+}
+
+// Query 返回 query 参数的首个值
+func (c *ParseContext) Query(name string) (string, bool) {
+	if c.query == nil {
+		c.query = c.x.Request.URL.Query()
+	}
+	if values := c.query[name]; len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// Header 返回请求头的首个值
+func (c *ParseContext) Header(name string) (string, bool) {
+	if values := c.x.Request.Header.Values(name); len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// Path 返回路径参数
+func (c *ParseContext) Path(name string) (string, bool) {
+	return c.x.PathParams.Try(name)
+}
+
+// Form 返回表单字段的首个值
+func (c *ParseContext) Form(name string) (string, bool) {
+	if values, ok := c.formValues(name); ok && len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+func (c *ParseContext) formValues(name string) ([]string, bool) {
+	req := c.x.Request
+	if req.MultipartForm != nil {
+		values, ok := req.MultipartForm.Value[name]
+		return values, ok
+	} else if req.Form != nil {
+		if values := req.Form[name]; len(values) > 0 {
+			return values, true
 		}
+	}
+	return nil, false
+}
 
-		var value any
-		var found bool
+// setBody 将流式请求体赋值给 io.Reader/io.ReadCloser/*BodyReader 字段
+func (c *ParseContext) setBody(fieldValue reflect.Value) error {
+	if !bodyReaderType.AssignableTo(fieldValue.Type()) {
+		return fmt.Errorf("body field must be io.Reader, io.ReadCloser or *vigo.BodyReader: %s", fieldValue.Type())
+	}
+	fieldValue.Set(reflect.ValueOf(newBodyReader(c.x)))
+	return nil
+}
 
-		switch fieldInfo.Source {
-		case sourceJSON:
-			if !parsedJSON {
-				if err := parseJSON(); err != nil {
-					return err
-				}
-			}
-			continue
-		case sourceForm:
-			if fieldInfo.IsFile {
-				if err := setFileValue(fieldValue, x.Request, fieldInfo.Name); err != nil {
-					return parserErr.WithArgs(fieldInfo.Name, err)
-				}
-				continue
-			}
-			if x.Request.MultipartForm != nil {
-				if fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() == reflect.String {
-					value, found = x.Request.MultipartForm.Value[fieldInfo.Name]
-				} else if valueList, ok := x.Request.MultipartForm.Value[fieldInfo.Name]; ok {
-					value = valueList[0]
-					found = true
-				}
-			} else if x.Request.Form != nil {
-				if formValues := x.Request.Form[fieldInfo.Name]; len(formValues) > 0 {
-					if fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() == reflect.String {
-						value = formValues
-					} else {
-						value = formValues[0]
-					}
-					found = true
-				}
-			}
-		case sourceQuery:
-			queryValues := x.Request.URL.Query()[fieldInfo.Name]
-			if len(queryValues) > 0 {
-				if fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() == reflect.String {
-					value = queryValues
-				} else {
-					value = queryValues[0]
-				}
-				found = true
-			}
-		case sourceHeader:
-			if headerValues := x.Request.Header.Values(fieldInfo.Name); len(headerValues) > 0 {
-				value = headerValues[0]
-				found = true
-			}
-		case sourcePath:
-			value, found = x.PathParams.Try(fieldInfo.Name)
+// Err 包装字段解析错误
+func (c *ParseContext) Err(name string, err error) error {
+	return parserErr.WithArgs(name, err)
+}
+
+// Missing 返回必填字段缺失错误
+func (c *ParseContext) Missing(name string) error {
+	return c.Err(name, ErrMissingArg.WithArgs(name))
+}
+
+// Field 使用反射解析单个字段, 用于生成代码不直接支持的类型
+func (c *ParseContext) Field(name string) error {
+	if c.info == nil {
+		c.rv = reflect.ValueOf(c.target).Elem()
+		c.info = getOrCreateTypeInfo(c.rv.Type())
+	}
+	for i := range c.info.Fields {
+		if c.info.Fields[i].Field.Name == name {
+			return c.parseField(&c.info.Fields[i])
 		}
+	}
+	return fmt.Errorf("field %s not found in %s", name, c.rv.Type())
+}
+
+func (c *ParseContext) parseField(fieldInfo *fieldInfo) error {
+	fieldValue := c.rv.Field(fieldInfo.Index)
+	if !fieldValue.CanSet() {
+		return nil
+	}
 
-		if err := setFieldValue(fieldValue, fieldInfo.Name, value, found, fieldInfo.DefaultVal); err != nil {
-			return parserErr.WithArgs(fieldInfo.Name, err)
+	var value any
+	var found bool
+
+	switch fieldInfo.Source {
+	case sourceJSON:
+		return c.JSON()
+	case sourceBody:
+		return c.setBody(fieldValue)
+	case sourceForm:
+		if fieldInfo.IsFile {
+			if err := setFileValue(fieldValue, c.x.Request, fieldInfo.Name); err != nil {
+				return c.Err(fieldInfo.Name, err)
+			}
+			return nil
+		}
+		if isArrayStyle(fieldInfo.Style) {
+			var values []string
+			values, found = c.formValues(fieldInfo.Name)
+			value = values
+		} else {
+			value, found = c.Form(fieldInfo.Name)
+		}
+	case sourceQuery:
+		if c.query == nil {
+			c.query = c.x.Request.URL.Query()
 		}
+		value, found = getQueryValue(c.query, fieldInfo.Name, fieldInfo.Style)
+	case sourceHeader:
+		value, found = c.Header(fieldInfo.Name)
+	case sourcePath:
+		value, found = c.Path(fieldInfo.Name)
 	}
 
+	if err := setFieldValue(fieldValue, fieldInfo.Name, value, found, fieldInfo.DefaultVal, fieldInfo.Format); err != nil {
+		return c.Err(fieldInfo.Name, err)
+	}
 	return nil
 }
 
@@ -326,14 +441,14 @@ func setFileValue(fieldValue reflect.Value, req *http.Request, fieldName string)
 }
 
 // setFieldValue 设置字段值
-func setFieldValue(fieldValue reflect.Value, fieldName string, value any, found bool, defaultTag *string) error {
+func setFieldValue(fieldValue reflect.Value, fieldName string, value any, found bool, defaultTag *string, format string) error {
 	isPointer := fieldValue.Kind() == reflect.Ptr
 
 	// 如果没有找到值
 	if !found || value == nil {
 		if defaultTag != nil && *defaultTag != "" {
 			// 使用默认值
-			return setValueFromString(fieldValue, *defaultTag, isPointer)
+			return setValueFromString(fieldValue, *defaultTag, isPointer, format)
 		} else if defaultTag != nil {
 			return nil
 		}
@@ -344,11 +459,11 @@ func setFieldValue(fieldValue reflect.Value, fieldName string, value any, found
 	}
 
 	// 转换并设置值
-	return setValue(fieldValue, value, isPointer)
+	return setValue(fieldValue, value, isPointer, format)
 }
 
 // setValue 设置值到字段
-func setValue(fieldValue reflect.Value, value any, isPointer bool) error {
+func setValue(fieldValue reflect.Value, value any, isPointer bool, format string) error {
 	if isPointer {
 		// 处理指针类型
 		if fieldValue.IsNil() {
@@ -357,6 +472,15 @@ func setValue(fieldValue reflect.Value, value any, isPointer bool) error {
 		fieldValue = fieldValue.Elem()
 	}
 
+	if str, ok := value.(string); ok {
+		if handled, err := setTextValue(fieldValue, str, format); handled {
+			return err
+		}
+	}
+	if obj, ok := value.(queryObject); ok {
+		return setObjectValue(fieldValue, obj, format)
+	}
+
 	switch fieldValue.Kind() {
 	case reflect.String:
 		strVal, err := convertToString(value)
@@ -394,10 +518,10 @@ func setValue(fieldValue reflect.Value, value any, isPointer bool) error {
 		fieldValue.SetBool(boolVal)
 
 	case reflect.Slice:
-		return setSliceValue(fieldValue, value)
+		return setSliceValue(fieldValue, value, format)
 
 	case reflect.Array:
-		return setArrayValue(fieldValue, value)
+		return setArrayValue(fieldValue, value, format)
 
 	case reflect.Map:
 		return setMapValue(fieldValue, value)
@@ -416,7 +540,7 @@ func setValue(fieldValue reflect.Value, value any, isPointer bool) error {
 }
 
 // setSliceValue 设置切片值
-func setSliceValue(fieldValue reflect.Value, value any) error {
+func setSliceValue(fieldValue reflect.Value, value any, format string) error {
 	elemType := fieldValue.Type().Elem()
 
 	// 处理 []byte
@@ -429,26 +553,14 @@ func setSliceValue(fieldValue reflect.Value, value any) error {
 		return nil
 	}
 
-	// 处理字符串切片
-	if elemType.Kind() == reflect.String {
-		if strSlice, ok := value.([]string); ok {
-			slice := reflect.MakeSlice(fieldValue.Type(), len(strSlice), len(strSlice))
-			for i, str := range strSlice {
-				slice.Index(i).SetString(str)
-			}
-			fieldValue.Set(slice)
-			return nil
-		}
-		// 尝试从逗号分隔的字符串解析
-		if str, ok := value.(string); ok {
-			parts := strings.Split(str, ",")
-			slice := reflect.MakeSlice(fieldValue.Type(), len(parts), len(parts))
-			for i, part := range parts {
-				slice.Index(i).SetString(strings.TrimSpace(part))
-			}
-			fieldValue.Set(slice)
-			return nil
+	// 处理字符串列表, 逐个元素转换
+	if parts, ok := toStringList(value); ok {
+		slice := reflect.MakeSlice(fieldValue.Type(), len(parts), len(parts))
+		if err := setListValue(slice, parts, format); err != nil {
+			return err
 		}
+		fieldValue.Set(slice)
+		return nil
 	}
 
 	// 尝试 JSON 解析
@@ -463,7 +575,14 @@ func setSliceValue(fieldValue reflect.Value, value any) error {
 }
 
 // setArrayValue 设置数组值
-func setArrayValue(fieldValue reflect.Value, value any) error {
+func setArrayValue(fieldValue reflect.Value, value any, format string) error {
+	if parts, ok := toStringList(value); ok {
+		if len(parts) > fieldValue.Len() {
+			return fmt.Errorf("too many values for %s: %d", fieldValue.Type(), len(parts))
+		}
+		return setListValue(fieldValue, parts, format)
+	}
+
 	// 尝试 JSON 解析
 	if rawMsg, ok := value.(json.RawMessage); ok {
 		if err := json.Unmarshal(rawMsg, fieldValue.Addr().Interface()); err != nil {
@@ -545,8 +664,95 @@ func setInterfaceValue(fieldValue reflect.Value, value any) error {
 	return nil
 }
 
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isTextType 检查字段类型是否以文本形式解析
+// time.Time/time.Duration/url.URL 以及实现了 encoding.TextUnmarshaler 的类型 (如 net.IP)
+func isTextType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType, durationType, urlType:
+		return true
+	}
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setTextValue 按文本类型设置字段值
+// 返回 handled=false 表示该字段不是文本类型, 由调用方按 Kind 继续处理
+func setTextValue(fieldValue reflect.Value, str string, format string) (bool, error) {
+	switch fieldValue.Type() {
+	case timeType:
+		var t time.Time
+		var err error
+		if format != "" {
+			t, err = parseTimeWithFormat(str, format)
+		} else {
+			t, err = convertToTime(str)
+		}
+		if err != nil {
+			return true, err
+		}
+		fieldValue.Set(reflect.ValueOf(t))
+		return true, nil
+	case durationType:
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return true, fmt.Errorf("cannot parse '%s' as duration: %w", str, err)
+		}
+		fieldValue.SetInt(int64(d))
+		return true, nil
+	case urlType:
+		u, err := url.Parse(str)
+		if err != nil {
+			return true, fmt.Errorf("cannot parse '%s' as url: %w", str, err)
+		}
+		fieldValue.Set(reflect.ValueOf(*u))
+		return true, nil
+	}
+	if fieldValue.CanAddr() {
+		if u, ok := fieldValue.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := u.UnmarshalText([]byte(str)); err != nil {
+				return true, fmt.Errorf("cannot parse '%s' as %s: %w", str, fieldValue.Type(), err)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseTimeWithFormat 按 format 标签解析时间
+// format 为 Go 时间布局, 或 unix/unixmilli 表示秒/毫秒时间戳
+func parseTimeWithFormat(str string, format string) (time.Time, error) {
+	switch format {
+	case "unix":
+		sec, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot parse '%s' as unix time: %w", str, err)
+		}
+		return time.Unix(sec, 0), nil
+	case "unixmilli":
+		ms, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot parse '%s' as unix milli time: %w", str, err)
+		}
+		return time.UnixMilli(ms), nil
+	}
+	t, err := time.Parse(format, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse '%s' as time with format '%s': %w", str, format, err)
+	}
+	return t, nil
+}
+
 // setValueFromString 从字符串设置默认值
-func setValueFromString(fieldValue reflect.Value, strValue string, isPointer bool) error {
+func setValueFromString(fieldValue reflect.Value, strValue string, isPointer bool, format string) error {
 	if isPointer {
 		if fieldValue.IsNil() {
 			fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
@@ -554,6 +760,10 @@ func setValueFromString(fieldValue reflect.Value, strValue string, isPointer boo
 		fieldValue = fieldValue.Elem()
 	}
 
+	if handled, err := setTextValue(fieldValue, strValue, format); handled {
+		return err
+	}
+
 	switch fieldValue.Kind() {
 	case reflect.String:
 		fieldValue.SetString(strValue)
@@ -587,27 +797,10 @@ func setValueFromString(fieldValue reflect.Value, strValue string, isPointer boo
 		fieldValue.SetBool(boolVal)
 
 	case reflect.Slice:
-		if fieldValue.Type().Elem().Kind() == reflect.String {
-			parts := strings.Split(strValue, ",")
-			slice := reflect.MakeSlice(fieldValue.Type(), len(parts), len(parts))
-			for i, part := range parts {
-				slice.Index(i).SetString(strings.TrimSpace(part))
-			}
-			fieldValue.Set(slice)
-		} else {
-			return fmt.Errorf("unsupported slice type for default value: %s", fieldValue.Type())
-		}
+		return setSliceValue(fieldValue, strValue, format)
 
-	case reflect.Struct:
-		if fieldValue.Type() == reflect.TypeOf(time.Time{}) {
-			timeVal, err := time.Parse(time.RFC3339, strValue)
-			if err != nil {
-				return fmt.Errorf("cannot parse '%s' as time: %w", strValue, err)
-			}
-			fieldValue.Set(reflect.ValueOf(timeVal))
-		} else {
-			return fmt.Errorf("unsupported struct type for default value: %s", fieldValue.Type())
-		}
+	case reflect.Array:
+		return setArrayValue(fieldValue, strValue, format)
 
 	default:
 		return fmt.Errorf("unsupported field type for default value: %s", fieldValue.Kind())
@@ -797,6 +990,11 @@ func convertToFloat(value any) (float64, error) {
 	}
 }
 
+// ParseBool 与 X.Parse 相同的宽松布尔解析, 支持 yes/no/on/off 等写法
+func ParseBool(s string) (bool, error) {
+	return convertToBool(s)
+}
+
 func convertToBool(value any) (bool, error) {
 	switch v := value.(type) {
 	case string:
@@ -884,3 +1082,226 @@ func convertToTime(value any) (time.Time, error) {
 		return time.Time{}, fmt.Errorf("cannot convert %T to time.Time", value)
 	}
 }
+
+// toStringList 将 []string 或逗号分隔的字符串转换为字符串列表
+func toStringList(value any) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case string:
+		parts := strings.Split(v, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts, true
+	}
+	return nil, false
+}
+
+// setListValue 将字符串列表逐个设置到 slice/array 元素中
+func setListValue(list reflect.Value, parts []string, format string) error {
+	isPointer := list.Type().Elem().Kind() == reflect.Ptr
+	for i, part := range parts {
+		if err := setValue(list.Index(i), part, isPointer, format); err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// query 数组/对象格式
+const (
+	styleForm       = "form"       // ids=1&ids=2 或 ids[]=1&ids[]=2
+	styleComma      = "comma"      // ids=1,2,3
+	stylePipe       = "pipe"       // ids=1|2|3
+	styleSpace      = "space"      // ids=1%202%203
+	styleDeepObject = "deepObject" // filter[status]=open&filter[owner]=me
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// getFieldStyle 获取字段的 query 格式, 未指定 style 标签时按类型推断
+// 切片/数组默认为 form, map/结构体默认为 deepObject, 其余为空
+func getFieldStyle(field reflect.StructField) string {
+	if style := field.Tag.Get("style"); style != "" {
+		return style
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isTextType(t) || isFileType(t) {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return ""
+		}
+		return styleForm
+	case reflect.Map, reflect.Struct:
+		if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+			return ""
+		}
+		return styleDeepObject
+	}
+	return ""
+}
+
+func isArrayStyle(style string) bool {
+	return style != "" && style != styleDeepObject
+}
+
+// getQueryValue 按 style 从 query 中读取字段值
+// 数组返回 []string, deepObject 返回 queryObject, 其余返回首个值
+func getQueryValue(query url.Values, name string, style string) (any, bool) {
+	switch style {
+	case "":
+		if values := query[name]; len(values) > 0 {
+			return values[0], true
+		}
+		return nil, false
+	case styleDeepObject:
+		obj := collectQueryObject(query, name)
+		return obj, obj != nil
+	}
+
+	values := append(append([]string{}, query[name]...), query[name+"[]"]...)
+	if len(values) == 0 {
+		return nil, false
+	}
+	sep := ""
+	switch style {
+	case styleComma:
+		sep = ","
+	case stylePipe:
+		sep = "|"
+	case styleSpace:
+		sep = " "
+	}
+	if sep == "" {
+		return values, true
+	}
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		for _, part := range strings.Split(v, sep) {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts, true
+}
+
+// queryObject 保存 deepObject 格式的 query 参数
+// key 为去掉前缀后的括号部分, 如 filter[owner][name] 的 key 为 [owner][name]
+type queryObject map[string][]string
+
+func collectQueryObject(query url.Values, prefix string) queryObject {
+	var obj queryObject
+	for k, v := range query {
+		if len(k) > len(prefix) && k[len(prefix)] == '[' && strings.HasPrefix(k, prefix) {
+			if obj == nil {
+				obj = make(queryObject)
+			}
+			obj[k[len(prefix):]] = v
+		}
+	}
+	return obj
+}
+
+// group 按第一层 key 分组, [a][b] 归入 a 组下的 [b]
+func (o queryObject) group() map[string]queryObject {
+	res := make(map[string]queryObject, len(o))
+	for k, v := range o {
+		if len(k) < 2 || k[0] != '[' {
+			continue
+		}
+		end := strings.IndexByte(k, ']')
+		if end < 0 {
+			continue
+		}
+		name, rest := k[1:end], k[end+1:]
+		if res[name] == nil {
+			res[name] = make(queryObject)
+		}
+		res[name][rest] = append(res[name][rest], v...)
+	}
+	return res
+}
+
+// leaf 返回叶子节点的值, 支持 key 和 key[] 两种写法
+func (o queryObject) leaf() ([]string, bool) {
+	var values []string
+	for k, v := range o {
+		if k != "" && k != "[]" {
+			return nil, false
+		}
+		values = append(values, v...)
+	}
+	return values, len(values) > 0
+}
+
+// setObjectValue 将 deepObject 参数设置到 map 或结构体
+func setObjectValue(fieldValue reflect.Value, obj queryObject, format string) error {
+	groups := obj.group()
+	switch fieldValue.Kind() {
+	case reflect.Map:
+		t := fieldValue.Type()
+		if fieldValue.IsNil() {
+			fieldValue.Set(reflect.MakeMap(t))
+		}
+		for name, sub := range groups {
+			key := reflect.New(t.Key()).Elem()
+			if err := setValue(key, name, false, ""); err != nil {
+				return fmt.Errorf("key %s: %w", name, err)
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if err := setObjectField(elem, sub, format); err != nil {
+				return fmt.Errorf("key %s: %w", name, err)
+			}
+			fieldValue.SetMapIndex(key, elem)
+		}
+		return nil
+	case reflect.Struct:
+		t := fieldValue.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			sub, ok := groups[name]
+			if !ok {
+				continue
+			}
+			if err := setObjectField(fieldValue.Field(i), sub, field.Tag.Get("format")); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot convert deepObject to %s", fieldValue.Type())
+}
+
+func setObjectField(fieldValue reflect.Value, sub queryObject, format string) error {
+	isPointer := fieldValue.Kind() == reflect.Ptr
+	values, ok := sub.leaf()
+	if !ok {
+		return setValue(fieldValue, sub, isPointer, format)
+	}
+	t := fieldValue.Type()
+	if isPointer {
+		t = t.Elem()
+	}
+	if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !isTextType(t) {
+		return setValue(fieldValue, values, isPointer, format)
+	}
+	return setValue(fieldValue, values[0], isPointer, format)
+}
@@ -3,12 +3,17 @@ package vigo
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 // Helper to create a basic X context
@@ -36,48 +41,71 @@ func createTestX(method, urlStr string, body interface{}) (*X, error) {
 	return x, nil
 }
 
+//go:generate go run ./cmd/vigogen -type=parseUserReq,parseComplexReq,parseQueryReq,parseFormReq,parsePathReq,parseHeaderReq,parseDefaultReq,parseMixReq,parseTextReq,parseListReq,parseBadListReq
+
+// parsers 每个用例同时经过反射解析和 vigogen 生成的 ParseVigo, 验证两者行为一致
+var parsers = []struct {
+	name  string
+	parse func(x *X, target VigoParser) error
+}{
+	{"reflect", func(x *X, target VigoParser) error { return x.parseReflect(target) }},
+	{"generated", func(x *X, target VigoParser) error { return target.ParseVigo(x) }},
+}
+
+type parseUserReq struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
 func TestParseJSON(t *testing.T) {
-	type User struct {
-		Name string `json:"name"`
-		Age  int    `json:"age"`
-	}
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			payload := parseUserReq{Name: "Alice", Age: 30}
+			x, _ := createTestX("POST", "/", payload)
+			defer release(x)
 
-	payload := User{Name: "Alice", Age: 30}
-	x, _ := createTestX("POST", "/", payload)
-	defer release(x)
+			var target parseUserReq
+			err := p.parse(x, &target)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
 
-	var target User
-	err := x.Parse(&target)
-	if err != nil {
-		t.Fatalf("Parse failed: %v", err)
+			if target.Name != payload.Name || target.Age != payload.Age {
+				t.Errorf("Expected %+v, got %+v", payload, target)
+			}
+		})
 	}
+}
 
-	if target.Name != payload.Name || target.Age != payload.Age {
-		t.Errorf("Expected %+v, got %+v", payload, target)
-	}
+// struct with both required (non-pointer) and optional (pointer) fields
+// for all 4 sources
+type parseComplexReq struct {
+	// Query
+	QReq string  `src:"query"`
+	QOpt *string `src:"query"`
+
+	// Header
+	HReq string  `src:"header"`
+	HOpt *string `src:"header"`
+
+	// Form
+	FReq string  `src:"form"`
+	FOpt *string `src:"form"`
+
+	// Path
+	PReq string  `src:"path"`
+	POpt *string `src:"path"`
 }
 
 func TestParseEmptyVsMissing(t *testing.T) {
-	// struct with both required (non-pointer) and optional (pointer) fields
-	// for all 4 sources
-	type ComplexReq struct {
-		// Query
-		QReq string  `src:"query"`
-		QOpt *string `src:"query"`
-
-		// Header
-		HReq string  `src:"header"`
-		HOpt *string `src:"header"`
-
-		// Form
-		FReq string  `src:"form"`
-		FOpt *string `src:"form"`
-
-		// Path
-		PReq string  `src:"path"`
-		POpt *string `src:"path"`
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			testParseEmptyVsMissing(t, p.parse)
+		})
 	}
+}
 
+func testParseEmptyVsMissing(t *testing.T, parse func(*X, VigoParser) error) {
 	// Helper to populate a request with ALL required fields set to empty strings
 	// This serves as a base for "Missing" tests (we remove one) and "Empty" tests (we keep all)
 	setupBaseReq := func() *X {
@@ -134,8 +162,8 @@ func TestParseEmptyVsMissing(t *testing.T) {
 
 		defer release(x)
 
-		var target ComplexReq
-		err := x.Parse(&target)
+		var target parseComplexReq
+		err := parse(x, &target)
 		if err != nil {
 			t.Errorf("Empty Values Case failed: %v", err)
 		}
@@ -220,8 +248,8 @@ func TestParseEmptyVsMissing(t *testing.T) {
 			defer release(x)
 			tt.remove(x)
 
-			var target ComplexReq
-			err := x.Parse(&target)
+			var target parseComplexReq
+			err := parse(x, &target)
 			if err == nil {
 				t.Errorf("%s: Expected error but got nil", tt.name)
 			} else {
@@ -235,160 +263,353 @@ func TestParseEmptyVsMissing(t *testing.T) {
 	}
 }
 
+type parseQueryReq struct {
+	Page int    `src:"query"`
+	Sort string `src:"query"`
+}
+
 func TestParseQuery(t *testing.T) {
-	type QueryReq struct {
-		Page int    `src:"query"`
-		Sort string `src:"query"`
-	}
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			x, _ := createTestX("GET", "/?Page=2&Sort=desc", nil)
+			defer release(x)
 
-	x, _ := createTestX("GET", "/?Page=2&Sort=desc", nil)
-	defer release(x)
+			var target parseQueryReq
+			err := p.parse(x, &target)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
 
-	var target QueryReq
-	err := x.Parse(&target)
-	if err != nil {
-		t.Fatalf("Parse failed: %v", err)
+			if target.Page != 2 {
+				t.Errorf("Expected Page=2, got %d", target.Page)
+			}
+			if target.Sort != "desc" {
+				t.Errorf("Expected Sort='desc', got '%s'", target.Sort)
+			}
+		})
 	}
+}
 
-	if target.Page != 2 {
-		t.Errorf("Expected Page=2, got %d", target.Page)
-	}
-	if target.Sort != "desc" {
-		t.Errorf("Expected Sort='desc', got '%s'", target.Sort)
-	}
+type parseFormReq struct {
+	Username string `src:"form"`
+	Active   bool   `src:"form"`
 }
 
 func TestParseForm(t *testing.T) {
-	type FormReq struct {
-		Username string `src:"form"`
-		Active   bool   `src:"form"`
-	}
-
-	form := url.Values{}
-	form.Add("Username", "bob")
-	form.Add("Active", "true")
-
-	req, _ := http.NewRequest("POST", "/", strings.NewReader(form.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("Username", "bob")
+			form.Add("Active", "true")
+
+			req, _ := http.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			x := acquire()
+			x.Request = req
+			x.writer = httptest.NewRecorder()
+			defer release(x)
 
-	x := acquire()
-	x.Request = req
-	x.writer = httptest.NewRecorder()
-	defer release(x)
+			var target parseFormReq
+			err := p.parse(x, &target)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
 
-	var target FormReq
-	err := x.Parse(&target)
-	if err != nil {
-		t.Fatalf("Parse failed: %v", err)
+			if target.Username != "bob" {
+				t.Errorf("Expected Username='bob', got '%s'", target.Username)
+			}
+			if !target.Active {
+				t.Errorf("Expected Active=true, got %v", target.Active)
+			}
+		})
 	}
+}
 
-	if target.Username != "bob" {
-		t.Errorf("Expected Username='bob', got '%s'", target.Username)
-	}
-	if !target.Active {
-		t.Errorf("Expected Active=true, got %v", target.Active)
-	}
+type parsePathReq struct {
+	ID   int    `src:"path"`
+	Slug string `src:"path"`
 }
 
 func TestParsePath(t *testing.T) {
-	type PathReq struct {
-		ID   int    `src:"path"`
-		Slug string `src:"path"`
-	}
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			x, _ := createTestX("GET", "/users/123/profile", nil)
+			defer release(x)
 
-	x, _ := createTestX("GET", "/users/123/profile", nil)
-	defer release(x)
+			// Manually set path params as if router matched them
+			x.PathParams = PathParams{Param{Key: "ID", Value: "123"}, Param{Key: "Slug", Value: "profile"}}
 
-	// Manually set path params as if router matched them
-	x.PathParams = PathParams{Param{Key: "ID", Value: "123"}, Param{Key: "Slug", Value: "profile"}}
+			var target parsePathReq
+			err := p.parse(x, &target)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
 
-	var target PathReq
-	err := x.Parse(&target)
-	if err != nil {
-		t.Fatalf("Parse failed: %v", err)
+			if target.ID != 123 {
+				t.Errorf("Expected ID=123, got %d", target.ID)
+			}
+			if target.Slug != "profile" {
+				t.Errorf("Expected Slug='profile', got '%s'", target.Slug)
+			}
+		})
 	}
+}
 
-	if target.ID != 123 {
-		t.Errorf("Expected ID=123, got %d", target.ID)
-	}
-	if target.Slug != "profile" {
-		t.Errorf("Expected Slug='profile', got '%s'", target.Slug)
-	}
+type parseHeaderReq struct {
+	AuthToken string `src:"header@X-Auth-Token"`
+	UserAgent string `src:"header@User-Agent"`
 }
 
 func TestParseHeader(t *testing.T) {
-	type HeaderReq struct {
-		AuthToken string `src:"header@X-Auth-Token"`
-		UserAgent string `src:"header@User-Agent"`
-	}
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			x, _ := createTestX("GET", "/", nil)
+			x.Request.Header.Set("X-Auth-Token", "secret123")
+			x.Request.Header.Set("User-Agent", "TestAgent")
+			defer release(x)
 
-	x, _ := createTestX("GET", "/", nil)
-	x.Request.Header.Set("X-Auth-Token", "secret123")
-	x.Request.Header.Set("User-Agent", "TestAgent")
-	defer release(x)
+			var target parseHeaderReq
+			err := p.parse(x, &target)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
 
-	var target HeaderReq
-	err := x.Parse(&target)
-	if err != nil {
-		t.Fatalf("Parse failed: %v", err)
+			if target.AuthToken != "secret123" {
+				t.Errorf("Expected AuthToken='secret123', got '%s'", target.AuthToken)
+			}
+			if target.UserAgent != "TestAgent" {
+				t.Errorf("Expected UserAgent='TestAgent', got '%s'", target.UserAgent)
+			}
+		})
 	}
+}
 
-	if target.AuthToken != "secret123" {
-		t.Errorf("Expected AuthToken='secret123', got '%s'", target.AuthToken)
-	}
-	if target.UserAgent != "TestAgent" {
-		t.Errorf("Expected UserAgent='TestAgent', got '%s'", target.UserAgent)
-	}
+type parseDefaultReq struct {
+	Page    int    `src:"query" default:"1"`
+	Keyword string `src:"query" default:"golang"`
 }
 
 func TestParseDefault(t *testing.T) {
-	type DefaultReq struct {
-		Page    int    `src:"query" default:"1"`
-		Keyword string `src:"query" default:"golang"`
-	}
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			x, _ := createTestX("GET", "/", nil) // No query params
+			defer release(x)
 
-	x, _ := createTestX("GET", "/", nil) // No query params
-	defer release(x)
+			var target parseDefaultReq
+			err := p.parse(x, &target)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
 
-	var target DefaultReq
-	err := x.Parse(&target)
-	if err != nil {
-		t.Fatalf("Parse failed: %v", err)
+			if target.Page != 1 {
+				t.Errorf("Expected Page=1, got %d", target.Page)
+			}
+			if target.Keyword != "golang" {
+				t.Errorf("Expected Keyword='golang', got '%s'", target.Keyword)
+			}
+		})
 	}
+}
 
-	if target.Page != 1 {
-		t.Errorf("Expected Page=1, got %d", target.Page)
-	}
-	if target.Keyword != "golang" {
-		t.Errorf("Expected Keyword='golang', got '%s'", target.Keyword)
-	}
+type parseMixReq struct {
+	ID    int    `src:"path"`
+	Page  int    `src:"query"`
+	Title string `json:"title"`
 }
 
 func TestParseMix(t *testing.T) {
-	type MixReq struct {
-		ID    int    `src:"path"`
-		Page  int    `src:"query"`
-		Title string `json:"title"`
-	}
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			payload := map[string]string{"title": "Hello"}
+			x, _ := createTestX("POST", "/posts/99?Page=5", payload)
+			defer release(x)
+			x.PathParams = PathParams{Param{Key: "ID", Value: "99"}}
 
-	payload := map[string]string{"title": "Hello"}
-	x, _ := createTestX("POST", "/posts/99?Page=5", payload)
-	defer release(x)
-	x.PathParams = PathParams{Param{Key: "ID", Value: "99"}}
+			var target parseMixReq
+			err := p.parse(x, &target)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
 
-	var target MixReq
-	err := x.Parse(&target)
-	if err != nil {
-		t.Fatalf("Parse failed: %v", err)
+			if target.ID != 99 {
+				t.Errorf("Expected ID=99, got %d", target.ID)
+			}
+			if target.Page != 5 {
+				t.Errorf("Expected Page=5, got %d", target.Page)
+			}
+			if target.Title != "Hello" {
+				t.Errorf("Expected Title='Hello', got '%s'", target.Title)
+			}
+		})
 	}
+}
 
-	if target.ID != 99 {
-		t.Errorf("Expected ID=99, got %d", target.ID)
+type testLevel int
+
+func (l *testLevel) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", b)
 	}
-	if target.Page != 5 {
-		t.Errorf("Expected Page=5, got %d", target.Page)
+	return nil
+}
+
+type parseTextReq struct {
+	Since   time.Time      `src:"query" format:"2006-01-02"`
+	Until   *time.Time     `src:"query" format:"unix"`
+	Timeout time.Duration  `src:"header@X-Timeout"`
+	Retry   time.Duration  `src:"query" default:"1m30s"`
+	IP      net.IP         `src:"path"`
+	Hook    *url.URL       `src:"query"`
+	Level   testLevel      `src:"query"`
+	Start   time.Time      `src:"query" default:"2024-01-02T03:04:05Z"`
+	Backoff *time.Duration `src:"query"`
+}
+
+func TestParseTextTypes(t *testing.T) {
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			x, _ := createTestX("GET", "/?Since=2025-03-04&Until=1700000000&Hook=https%3A%2F%2Fexample.com%2Fcb&Level=high", nil)
+			defer release(x)
+			x.Request.Header.Set("X-Timeout", "250ms")
+			x.PathParams = PathParams{{Key: "IP", Value: "10.0.0.1"}}
+
+			var target parseTextReq
+			if err := p.parse(x, &target); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if !target.Since.Equal(time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("Since: got %v", target.Since)
+			}
+			if target.Until == nil || target.Until.Unix() != 1700000000 {
+				t.Errorf("Until: got %v", target.Until)
+			}
+			if target.Timeout != 250*time.Millisecond {
+				t.Errorf("Timeout: got %v", target.Timeout)
+			}
+			if target.Retry != 90*time.Second {
+				t.Errorf("Retry: got %v", target.Retry)
+			}
+			if !target.IP.Equal(net.ParseIP("10.0.0.1")) {
+				t.Errorf("IP: got %v", target.IP)
+			}
+			if target.Hook == nil || target.Hook.Host != "example.com" || target.Hook.Path != "/cb" {
+				t.Errorf("Hook: got %v", target.Hook)
+			}
+			if target.Level != 2 {
+				t.Errorf("Level: got %v", target.Level)
+			}
+			if !target.Start.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+				t.Errorf("Start: got %v", target.Start)
+			}
+			if target.Backoff != nil {
+				t.Errorf("Backoff: expected nil, got %v", target.Backoff)
+			}
+
+			x2, _ := createTestX("GET", "/?Since=03-04-2025&Level=low", nil)
+			defer release(x2)
+			x2.Request.Header.Set("X-Timeout", "1s")
+			x2.PathParams = PathParams{{Key: "IP", Value: "10.0.0.1"}}
+			if err := p.parse(x2, &parseTextReq{}); err == nil || !strings.Contains(err.Error(), "Since") {
+				t.Errorf("Expected format error for Since, got %v", err)
+			}
+		})
 	}
-	if target.Title != "Hello" {
-		t.Errorf("Expected Title='Hello', got '%s'", target.Title)
+}
+
+type parseFilter struct {
+	Status string    `json:"status"`
+	Owner  *string   `json:"owner"`
+	Tags   []string  `json:"tags"`
+	Since  time.Time `json:"since" format:"2006-01-02"`
+}
+
+type parseListReq struct {
+	IDs     []int             `src:"query@ids"`
+	Names   []string          `src:"query@names" style:"comma"`
+	Scores  []float64         `src:"query@scores" style:"pipe"`
+	UUIDs   []uuid.UUID       `src:"query@uuids" style:"comma"`
+	Filter  parseFilter       `src:"query@filter"`
+	Labels  map[string]string `src:"query@labels"`
+	Limits  map[string]int    `src:"query@limits"`
+	Missing *[]int            `src:"query@missing"`
+	Def     []int             `src:"query@def" default:"7,8"`
+}
+
+type parseBadListReq struct {
+	IDs []int `src:"query@ids"`
+}
+
+func TestParseQueryStyles(t *testing.T) {
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			id1, id2 := uuid.New(), uuid.New()
+			q := url.Values{}
+			q.Add("ids", "1")
+			q.Add("ids[]", "2")
+			q.Add("ids[]", "3")
+			q.Add("names", "a, b,c")
+			q.Add("scores", "1.5|2")
+			q.Add("uuids", id1.String()+","+id2.String())
+			q.Add("filter[status]", "open")
+			q.Add("filter[owner]", "me")
+			q.Add("filter[tags][]", "x")
+			q.Add("filter[tags][]", "y")
+			q.Add("filter[since]", "2025-01-02")
+			q.Add("labels[env]", "prod")
+			q.Add("labels[team]", "core")
+			q.Add("limits[cpu]", "4")
+
+			x, _ := createTestX("GET", "/?"+q.Encode(), nil)
+			defer release(x)
+
+			var target parseListReq
+			if err := p.parse(x, &target); err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if fmt.Sprint(target.IDs) != "[1 2 3]" {
+				t.Errorf("IDs: got %v", target.IDs)
+			}
+			if strings.Join(target.Names, "|") != "a|b|c" {
+				t.Errorf("Names: got %v", target.Names)
+			}
+			if fmt.Sprint(target.Scores) != "[1.5 2]" {
+				t.Errorf("Scores: got %v", target.Scores)
+			}
+			if len(target.UUIDs) != 2 || target.UUIDs[0] != id1 || target.UUIDs[1] != id2 {
+				t.Errorf("UUIDs: got %v", target.UUIDs)
+			}
+			if target.Filter.Status != "open" || target.Filter.Owner == nil || *target.Filter.Owner != "me" {
+				t.Errorf("Filter: got %+v", target.Filter)
+			}
+			if strings.Join(target.Filter.Tags, ",") != "x,y" || target.Filter.Since.Day() != 2 {
+				t.Errorf("Filter: got %+v", target.Filter)
+			}
+			if target.Labels["env"] != "prod" || target.Labels["team"] != "core" {
+				t.Errorf("Labels: got %v", target.Labels)
+			}
+			if target.Limits["cpu"] != 4 {
+				t.Errorf("Limits: got %v", target.Limits)
+			}
+			if target.Missing != nil {
+				t.Errorf("Missing: expected nil, got %v", target.Missing)
+			}
+			if fmt.Sprint(target.Def) != "[7 8]" {
+				t.Errorf("Def: got %v", target.Def)
+			}
+
+			x2, _ := createTestX("GET", "/?ids=1&ids=x", nil)
+			defer release(x2)
+			if err := p.parse(x2, &parseBadListReq{}); err == nil || !strings.Contains(err.Error(), "index 1") {
+				t.Errorf("Expected element error, got %v", err)
+			}
+		})
 	}
 }
@@ -0,0 +1,297 @@
+// Code generated by vigogen. DO NOT EDIT.
+
+package vigo
+
+import (
+	"strconv"
+)
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseUserReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if err := c.JSON(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseComplexReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.Query("QReq"); ok {
+		r.QReq = v
+	} else {
+		return c.Missing("QReq")
+	}
+	if v, ok := c.Query("QOpt"); ok {
+		if r.QOpt == nil {
+			r.QOpt = new(string)
+		}
+		*r.QOpt = v
+	}
+	if v, ok := c.Header("HReq"); ok {
+		r.HReq = v
+	} else {
+		return c.Missing("HReq")
+	}
+	if v, ok := c.Header("HOpt"); ok {
+		if r.HOpt == nil {
+			r.HOpt = new(string)
+		}
+		*r.HOpt = v
+	}
+	if v, ok := c.Form("FReq"); ok {
+		r.FReq = v
+	} else {
+		return c.Missing("FReq")
+	}
+	if v, ok := c.Form("FOpt"); ok {
+		if r.FOpt == nil {
+			r.FOpt = new(string)
+		}
+		*r.FOpt = v
+	}
+	if v, ok := c.Path("PReq"); ok {
+		r.PReq = v
+	} else {
+		return c.Missing("PReq")
+	}
+	if v, ok := c.Path("POpt"); ok {
+		if r.POpt == nil {
+			r.POpt = new(string)
+		}
+		*r.POpt = v
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseQueryReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.Query("Page"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return c.Err("Page", err)
+		}
+		r.Page = int(n)
+	} else {
+		return c.Missing("Page")
+	}
+	if v, ok := c.Query("Sort"); ok {
+		r.Sort = v
+	} else {
+		return c.Missing("Sort")
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseFormReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.Form("Username"); ok {
+		r.Username = v
+	} else {
+		return c.Missing("Username")
+	}
+	if v, ok := c.Form("Active"); ok {
+		b, err := ParseBool(v)
+		if err != nil {
+			return c.Err("Active", err)
+		}
+		r.Active = b
+	} else {
+		return c.Missing("Active")
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parsePathReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.Path("ID"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return c.Err("ID", err)
+		}
+		r.ID = int(n)
+	} else {
+		return c.Missing("ID")
+	}
+	if v, ok := c.Path("Slug"); ok {
+		r.Slug = v
+	} else {
+		return c.Missing("Slug")
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseHeaderReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.Header("X-Auth-Token"); ok {
+		r.AuthToken = v
+	} else {
+		return c.Missing("X-Auth-Token")
+	}
+	if v, ok := c.Header("User-Agent"); ok {
+		r.UserAgent = v
+	} else {
+		return c.Missing("User-Agent")
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseDefaultReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.Query("Page"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return c.Err("Page", err)
+		}
+		r.Page = int(n)
+	} else {
+		r.Page = int(1)
+	}
+	if v, ok := c.Query("Keyword"); ok {
+		r.Keyword = v
+	} else {
+		r.Keyword = "golang"
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseMixReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if v, ok := c.Path("ID"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return c.Err("ID", err)
+		}
+		r.ID = int(n)
+	} else {
+		return c.Missing("ID")
+	}
+	if v, ok := c.Query("Page"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return c.Err("Page", err)
+		}
+		r.Page = int(n)
+	} else {
+		return c.Missing("Page")
+	}
+	if err := c.JSON(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseTextReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if err := c.Field("Since"); err != nil {
+		return err
+	}
+	if err := c.Field("Until"); err != nil {
+		return err
+	}
+	if err := c.Field("Timeout"); err != nil {
+		return err
+	}
+	if err := c.Field("Retry"); err != nil {
+		return err
+	}
+	if err := c.Field("IP"); err != nil {
+		return err
+	}
+	if err := c.Field("Hook"); err != nil {
+		return err
+	}
+	if err := c.Field("Level"); err != nil {
+		return err
+	}
+	if err := c.Field("Start"); err != nil {
+		return err
+	}
+	if err := c.Field("Backoff"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseListReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if err := c.Field("IDs"); err != nil {
+		return err
+	}
+	if err := c.Field("Names"); err != nil {
+		return err
+	}
+	if err := c.Field("Scores"); err != nil {
+		return err
+	}
+	if err := c.Field("UUIDs"); err != nil {
+		return err
+	}
+	if err := c.Field("Filter"); err != nil {
+		return err
+	}
+	if err := c.Field("Labels"); err != nil {
+		return err
+	}
+	if err := c.Field("Limits"); err != nil {
+		return err
+	}
+	if err := c.Field("Missing"); err != nil {
+		return err
+	}
+	if err := c.Field("Def"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseVigo 由 vigogen 生成, 与 X.Parse 的反射解析行为一致
+func (r *parseBadListReq) ParseVigo(x *X) error {
+	c, err := x.NewParseContext(r)
+	if err != nil {
+		return err
+	}
+	if err := c.Field("IDs"); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+//
+// xproxy.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxiesKey 路由/请求变量名, 保存 *TrustedProxies; Application 按 Config.TrustedProxies 自动设置
+const TrustedProxiesKey = "vigo.trusted_proxies"
+
+const (
+	// HeaderXForwardedFor 可信代理默认采用的代理头, 配合 X-Forwarded-Proto/X-Forwarded-Host/X-Real-IP
+	HeaderXForwardedFor = "X-Forwarded-For"
+	// HeaderForwarded RFC 7239 Forwarded 头
+	HeaderForwarded = "Forwarded"
+)
+
+// TrustedProxies 可信代理网段, 只有来自可信代理的 X-Forwarded-*/Forwarded 头才会被采用
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+	// Header 代理追加的头, HeaderXForwardedFor (默认) 或 HeaderForwarded, 只解析该头, 另一个头由客户端控制, 直接忽略
+	Header string
+}
+
+// ParseTrustedProxies 解析 CIDR 或单个 IP, 如 10.0.0.0/8, 127.0.0.1, ::1
+func ParseTrustedProxies(cidrs ...string) (*TrustedProxies, error) {
+	t := &TrustedProxies{}
+	for _, s := range cidrs {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if strings.Contains(s, "/") {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+			}
+			t.prefixes = append(t.prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+		}
+		addr = addr.Unmap()
+		t.prefixes = append(t.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return t, nil
+}
+
+func (t *TrustedProxies) forwarded() bool {
+	return strings.EqualFold(t.Header, HeaderForwarded)
+}
+
+// Contains 判断 ip 是否属于可信代理
+func (t *TrustedProxies) Contains(ip netip.Addr) bool {
+	if t == nil || !ip.IsValid() {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, p := range t.prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply 作为中间件为当前请求设置可信代理
+func (t *TrustedProxies) Apply(x *X) {
+	x.Set(TrustedProxiesKey, t)
+}
+
+func requestProxies(r *http.Request) *TrustedProxies {
+	t, _ := r.Context().Value(TrustedProxiesKey).(*TrustedProxies)
+	return t
+}
+
+// peerAddr 直接连接的对端地址
+func peerAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, _ := netip.ParseAddr(host)
+	return addr.Unmap()
+}
+
+// parseForwarded 解析 RFC 7239 Forwarded 头, 返回按出现顺序排列的各段参数
+func parseForwarded(values []string) []map[string]string {
+	var res []map[string]string
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			m := map[string]string{}
+			for _, pair := range strings.Split(elem, ";") {
+				k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				m[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(val), `"`)
+			}
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// parseNode 解析 for= 或 X-Forwarded-For 中的节点, 去掉端口和方括号
+func parseNode(s string) netip.Addr {
+	s = strings.TrimSpace(s)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap()
+	}
+	addr, _ := netip.ParseAddr(strings.Trim(s, "[]"))
+	return addr.Unmap()
+}
+
+// forwardedChain 返回代理链上的客户端地址, 只解析配置的代理头
+func forwardedChain(r *http.Request, t *TrustedProxies) []string {
+	var chain []string
+	if t.forwarded() {
+		for _, m := range parseForwarded(r.Header.Values(HeaderForwarded)) {
+			chain = append(chain, m["for"])
+		}
+		return chain
+	}
+	for _, v := range r.Header.Values(HeaderXForwardedFor) {
+		for _, ip := range strings.Split(v, ",") {
+			chain = append(chain, strings.TrimSpace(ip))
+		}
+	}
+	return chain
+}
+
+// proxyHop 从右向左跳过可信代理, 第一个不可信的地址即客户端地址;
+// hop 为客户端所在节点距代理链右端的位置, 对端不可信时 ok 为 false
+func proxyHop(r *http.Request, t *TrustedProxies) (client netip.Addr, hop int, ok bool) {
+	peer := peerAddr(r)
+	if !t.Contains(peer) {
+		return peer, 0, false
+	}
+	chain := forwardedChain(r, t)
+	if len(chain) == 0 {
+		if t.forwarded() {
+			return peer, 0, true
+		}
+		if ip := parseNode(r.Header.Get("X-Real-IP")); ip.IsValid() {
+			return ip, 0, true
+		}
+		return peer, 0, true
+	}
+	client = peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseNode(chain[i])
+		if !ip.IsValid() {
+			// unknown 或混淆的节点, 无法继续追溯
+			break
+		}
+		client, hop = ip, len(chain)-1-i
+		if !t.Contains(ip) {
+			break
+		}
+	}
+	return client, hop, true
+}
+
+// clientIP 返回客户端地址, 对端不是可信代理时即对端地址
+func clientIP(r *http.Request, t *TrustedProxies) string {
+	client, _, _ := proxyHop(r, t)
+	if client.IsValid() {
+		return client.String()
+	}
+	return ""
+}
+
+// forwardedValue 来自可信代理时返回 Forwarded 中的参数或对应的 X-Forwarded-* 头,
+// 取与客户端地址同一跳的值, 该跳缺失时取其右侧由可信代理添加的值, 不采用客户端可控的更左侧的值
+func forwardedValue(r *http.Request, t *TrustedProxies, param, header string) string {
+	_, hop, ok := proxyHop(r, t)
+	if !ok {
+		return ""
+	}
+	var values []string
+	if t.forwarded() {
+		for _, m := range parseForwarded(r.Header.Values(HeaderForwarded)) {
+			values = append(values, m[param])
+		}
+	} else {
+		for _, v := range r.Header.Values(header) {
+			for _, item := range strings.Split(v, ",") {
+				values = append(values, strings.TrimSpace(item))
+			}
+		}
+	}
+	for i := max(len(values)-1-hop, 0); i < len(values); i++ {
+		if values[i] != "" {
+			return values[i]
+		}
+	}
+	return ""
+}
+
+// RequestScheme 返回请求的协议 http/https, 来自可信代理时采用 Forwarded proto 或 X-Forwarded-Proto
+func RequestScheme(r *http.Request) string {
+	return requestScheme(r, requestProxies(r))
+}
+
+func requestScheme(r *http.Request, t *TrustedProxies) string {
+	if v := strings.ToLower(forwardedValue(r, t, "proto", "X-Forwarded-Proto")); v == "http" || v == "https" {
+		return v
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// RequestHost 返回请求的 Host, 来自可信代理时采用 Forwarded host 或 X-Forwarded-Host
+func RequestHost(r *http.Request) string {
+	return requestHost(r, requestProxies(r))
+}
+
+func requestHost(r *http.Request, t *TrustedProxies) string {
+	if v := forwardedValue(r, t, "host", "X-Forwarded-Host"); v != "" {
+		return v
+	}
+	return r.Host
+}
+
+// ClientIP 返回客户端 IP, 只信任来自可信代理的 TrustedProxies.Header 指定的头
+func ClientIP(r *http.Request) string {
+	return clientIP(r, requestProxies(r))
+}
+
+func (x *X) trustedProxies() *TrustedProxies {
+	t, _ := x.Get(TrustedProxiesKey).(*TrustedProxies)
+	return t
+}
+
+// Scheme 返回请求的协议 http/https
+func (x *X) Scheme() string {
+	return requestScheme(x.Request, x.trustedProxies())
+}
+
+// Host 返回请求的 Host, 可能带端口
+func (x *X) Host() string {
+	return requestHost(x.Request, x.trustedProxies())
+}
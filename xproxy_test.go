@@ -0,0 +1,94 @@
+package vigo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8", "::1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fwd, _ := ParseTrustedProxies("10.0.0.0/8")
+	fwd.Header = HeaderForwarded
+	cases := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		trusted *TrustedProxies
+		ip      string
+		scheme  string
+		host    string
+	}{
+		{"untrusted peer", "1.2.3.4:5000", map[string]string{"X-Forwarded-For": "9.9.9.9", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil"}, proxies, "1.2.3.4", "http", "example.com"},
+		{"no trusted config", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "9.9.9.9"}, nil, "10.0.0.1", "http", "example.com"},
+		{"spoofed left entry", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2, 10.0.0.2", "X-Forwarded-Proto": "https, http, http", "X-Forwarded-Host": "evil, api.example.com, internal"}, proxies, "2.2.2.2", "http", "api.example.com"},
+		{"single proto from proxy", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2", "X-Forwarded-Proto": "https"}, proxies, "2.2.2.2", "https", "example.com"},
+		{"passed through forwarded", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "2.2.2.2", "Forwarded": "for=6.6.6.6;proto=https;host=evil"}, proxies, "2.2.2.2", "http", "example.com"},
+		{"all trusted", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, proxies, "10.0.0.3", "http", "example.com"},
+		{"unknown hop", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "unknown, 10.0.0.2"}, proxies, "10.0.0.2", "http", "example.com"},
+		{"x-real-ip", "[::1]:5000", map[string]string{"X-Real-IP": "3.3.3.3"}, proxies, "3.3.3.3", "http", "example.com"},
+		{"forwarded", "10.0.0.1:5000", map[string]string{
+			"Forwarded":       `for=192.0.2.60;proto=http;host=evil, for="[2001:db8::17]:4711";proto=https;host=api.example.com`,
+			"X-Forwarded-For": "9.9.9.9",
+		}, fwd, "2001:db8::17", "https", "api.example.com"},
+		{"forwarded spoofed left entry", "10.0.0.1:5000", map[string]string{
+			"Forwarded": `for=6.6.6.6;proto=http;host=evil, for=2.2.2.2;proto=https;host=api.example.com, for=10.0.0.2`,
+		}, fwd, "2.2.2.2", "https", "api.example.com"},
+		{"passed through x-forwarded-for", "10.0.0.1:5000", map[string]string{"X-Forwarded-For": "6.6.6.6", "X-Real-IP": "6.6.6.6"}, fwd, "10.0.0.1", "http", "example.com"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		r.RemoteAddr = c.remote
+		for k, v := range c.headers {
+			r.Header.Set(k, v)
+		}
+		x := acquire()
+		x.Request = r
+		if c.trusted != nil {
+			x.Set(TrustedProxiesKey, c.trusted)
+		}
+		if ip := x.GetRemoteIP(); ip != c.ip {
+			t.Errorf("%s: ip = %q, want %q", c.name, ip, c.ip)
+		}
+		if s := x.Scheme(); s != c.scheme {
+			t.Errorf("%s: scheme = %q, want %q", c.name, s, c.scheme)
+		}
+		if h := x.Host(); h != c.host {
+			t.Errorf("%s: host = %q, want %q", c.name, h, c.host)
+		}
+		release(x)
+	}
+	if _, err := ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("expected invalid cidr error")
+	}
+}
+
+func TestServerTrustedProxies(t *testing.T) {
+	app, base := newTestServer(t, WithTrustedProxies("127.0.0.1"))
+	app.Router().Get("/ip", func(x *X) { x.WriteString(x.GetRemoteIP() + " " + ClientIP(x.Request)) })
+	go app.RunContext(context.Background())
+	defer app.Shutdown(context.Background())
+
+	req, _ := http.NewRequest(http.MethodGet, base+"/ip", nil)
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if string(b) != "2.2.2.2 2.2.2.2" {
+		t.Errorf("unexpected client ip %q", b)
+	}
+	if _, err := NewServer(WithTrustedProxies("bad")); err == nil {
+		t.Error("expected invalid trusted proxy error")
+	}
+	if _, err := NewServer(WithTrustedProxies("127.0.0.1"), WithForwardedHeader("X-Real-IP")); err == nil {
+		t.Error("expected invalid forwarded header error")
+	}
+}
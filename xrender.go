@@ -0,0 +1,546 @@
+//
+// xrender.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RenderFormatsKey 路由变量名, 限制该路由可协商的响应格式, 值为 []string 媒体类型, 为空时不限制;
+// 文档只为设置了该变量的路由列出可协商的格式
+//
+//	router.SetVar(vigo.RenderFormatsKey, []string{"application/json", "text/csv"})
+const RenderFormatsKey = "vigo.render_formats"
+
+// Renderer 响应格式, 由 x.Render 按 Accept 协商选择
+type Renderer struct {
+	// MediaType 主媒体类型, 如 application/json
+	MediaType string
+	// Aliases 同样可匹配的媒体类型
+	Aliases []string
+	// ContentType 响应头, 为空时使用 MediaType
+	ContentType string
+	// Match 判断能否渲染该类型的数据, 为 nil 时接受任何类型
+	Match func(t reflect.Type) bool
+	// Render 写出数据
+	Render func(w io.Writer, data any) error
+}
+
+func (r *Renderer) match(t reflect.Type) bool {
+	if r.Match == nil || t == nil {
+		return true
+	}
+	return r.Match(t)
+}
+
+var (
+	renderersMu sync.RWMutex
+	// renderers 注册时整体替换 (copy-on-write), 协商中取到的切片不会被修改
+	renderers []*Renderer
+)
+
+// RegisterRenderer 注册响应格式, 相同 MediaType 会被替换
+// 注册顺序即 Accept 为空或 */* 时的优先级
+func RegisterRenderer(r *Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	next := make([]*Renderer, len(renderers), len(renderers)+1)
+	copy(next, renderers)
+	renderers = next
+	for i, item := range next {
+		if item.MediaType == r.MediaType {
+			next[i] = r
+			return
+		}
+	}
+	renderers = append(next, r)
+}
+
+func getRenderers() []*Renderer {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	return renderers
+}
+
+func init() {
+	RegisterRenderer(&Renderer{
+		MediaType:   "application/json",
+		Aliases:     []string{"text/json"},
+		ContentType: "application/json; charset=utf-8",
+		Render: func(w io.Writer, data any) error {
+			return json.NewEncoder(w).Encode(data)
+		},
+	})
+	RegisterRenderer(&Renderer{
+		MediaType:   "application/xml",
+		Aliases:     []string{"text/xml"},
+		ContentType: "application/xml; charset=utf-8",
+		Match: func(t reflect.Type) bool {
+			return derefType(t).Kind() != reflect.Map
+		},
+		Render: func(w io.Writer, data any) error {
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return err
+			}
+			return xml.NewEncoder(w).Encode(data)
+		},
+	})
+	RegisterRenderer(&Renderer{
+		MediaType:   "application/yaml",
+		Aliases:     []string{"application/x-yaml", "text/yaml"},
+		ContentType: "application/yaml; charset=utf-8",
+		Render: func(w io.Writer, data any) error {
+			enc := yaml.NewEncoder(w)
+			if err := enc.Encode(data); err != nil {
+				return err
+			}
+			return enc.Close()
+		},
+	})
+	RegisterRenderer(&Renderer{
+		MediaType: "application/msgpack",
+		Aliases:   []string{"application/x-msgpack", "application/vnd.msgpack"},
+		Render:    renderMsgpack,
+	})
+	RegisterRenderer(&Renderer{
+		MediaType:   "text/csv",
+		ContentType: "text/csv; charset=utf-8",
+		Match:       isStructList,
+		Render:      renderCSV,
+	})
+	RegisterRenderer(&Renderer{
+		MediaType:   "text/plain",
+		ContentType: "text/plain; charset=utf-8",
+		Match:       isTextRenderable,
+		Render: func(w io.Writer, data any) error {
+			var err error
+			switch v := data.(type) {
+			case nil:
+			case []byte:
+				_, err = w.Write(v)
+			case error:
+				_, err = io.WriteString(w, v.Error())
+			default:
+				_, err = fmt.Fprint(w, v)
+			}
+			return err
+		},
+	})
+}
+
+// Render 按请求的 Accept 头选择响应格式并写出数据, 无可用格式时返回 ErrNotAcceptable
+// iter.Seq/iter.Seq2/<-chan 交给 x.Stream 逐个输出
+//
+// 传入模板名和数据时使用 TemplateKey 设置的模板集渲染 HTML:
+//
+//	x.Render("pages/index.html", data)
+func (x *X) Render(data any, args ...any) error {
+	if name, ok := data.(string); ok && len(args) == 1 {
+		return x.renderTemplate(name, args[0])
+	}
+	if _, ok := streamElemType(reflect.TypeOf(data)); ok {
+		return x.Stream(data)
+	}
+	addVary(x.Header(), "Accept")
+	allowed, _ := x.Get(RenderFormatsKey).([]string)
+	r := negotiateRenderer(x.Request.Header.Get("Accept"), reflect.TypeOf(data), allowed)
+	if r == nil {
+		return ErrNotAcceptable.WithArgs(x.Request.Header.Get("Accept"))
+	}
+	var buf bytes.Buffer
+	if err := r.Render(&buf, data); err != nil {
+		return err
+	}
+	contentType := r.ContentType
+	if contentType == "" {
+		contentType = r.MediaType
+	}
+	x.Header().Set("Content-Type", contentType)
+	_, err := x.Write(buf.Bytes())
+	return err
+}
+
+// RenderFormats 返回可用于渲染该类型的媒体类型, 用于生成文档
+func RenderFormats(t reflect.Type, allowed []string) []string {
+	var res []string
+	for _, r := range getRenderers() {
+		if r.match(t) && isAllowedRenderer(r, allowed) {
+			res = append(res, r.MediaType)
+		}
+	}
+	return res
+}
+
+func addVary(h http.Header, key string) {
+	for _, v := range h["Vary"] {
+		for _, item := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), key) {
+				return
+			}
+		}
+	}
+	h["Vary"] = append(h["Vary"], key)
+}
+
+func isAllowedRenderer(r *Renderer, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, item := range allowed {
+		if strings.EqualFold(item, r.MediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+type acceptItem struct {
+	typ, sub string
+	q        float64
+}
+
+func parseAccept(header string) []acceptItem {
+	var items []acceptItem
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		item := acceptItem{q: 1}
+		params := strings.Split(part, ";")
+		mt := strings.ToLower(strings.TrimSpace(params[0]))
+		item.typ, item.sub, _ = strings.Cut(mt, "/")
+		if item.sub == "" {
+			item.sub = "*"
+		}
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					item.q = q
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// acceptQuality 返回媒体类型在 Accept 中最具体匹配项的 q 值及其位置, 未匹配时位置为 len(items)
+// exact 为 true 时只接受完全匹配, 用于别名, 避免 text/json 命中 text/*
+func acceptQuality(items []acceptItem, mediaType string, exact bool) (float64, int) {
+	typ, sub, _ := strings.Cut(strings.ToLower(mediaType), "/")
+	best, q, idx := 0, 0.0, len(items)
+	for i, item := range items {
+		score := 0
+		switch {
+		case item.typ == typ && item.sub == sub:
+			score = 3
+		case exact:
+		case item.typ == typ && item.sub == "*":
+			score = 2
+		case item.typ == "*" && item.sub == "*":
+			score = 1
+		}
+		if score > best {
+			best, q, idx = score, item.q, i
+		}
+	}
+	return q, idx
+}
+
+func negotiateRenderer(accept string, t reflect.Type, allowed []string) *Renderer {
+	items := parseAccept(accept)
+	var best *Renderer
+	bestQ, bestIdx := 0.0, 0
+	for _, r := range getRenderers() {
+		if !r.match(t) || !isAllowedRenderer(r, allowed) {
+			continue
+		}
+		if len(items) == 0 {
+			return r
+		}
+		q, idx := 0.0, len(items)
+		for i, name := range append([]string{r.MediaType}, r.Aliases...) {
+			if nq, nidx := acceptQuality(items, name, i > 0); nq > q || (nq == q && nidx < idx) {
+				q, idx = nq, nidx
+			}
+		}
+		// q 相同时取 Accept 中靠前的类型, 再按注册顺序
+		if q > bestQ || (q == bestQ && q > 0 && idx < bestIdx) {
+			best, bestQ, bestIdx = r, q, idx
+		}
+	}
+	return best
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func isTextRenderable(t reflect.Type) bool {
+	if t.Implements(stringerType) || t.Implements(errorType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+func isStructList(t reflect.Type) bool {
+	t = derefType(t)
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return false
+	}
+	return derefType(t.Elem()).Kind() == reflect.Struct
+}
+
+// renderCSV 将结构体切片写为 CSV, 表头使用 json 字段名
+func renderCSV(w io.Writer, data any) error {
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	et := derefType(rv.Type().Elem())
+	var header []string
+	var index []int
+	for i := 0; i < et.NumField(); i++ {
+		field := et.Field(i)
+		if field.PkgPath != "" || field.Anonymous {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		header = append(header, name)
+		index = append(index, i)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(index))
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		for item.Kind() == reflect.Ptr && !item.IsNil() {
+			item = item.Elem()
+		}
+		for j, idx := range index {
+			row[j] = ""
+			if item.Kind() == reflect.Struct {
+				s, err := csvValue(item.Field(idx))
+				if err != nil {
+					return err
+				}
+				row[j] = s
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			b, err := m.MarshalText()
+			return string(b), err
+		}
+		v = v.Elem()
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		b, err := json.Marshal(v.Interface())
+		return string(b), err
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// renderMsgpack 先按 JSON 规则转换 (沿用 json 标签和 MarshalJSON), 再编码为 MessagePack
+func renderMsgpack(w io.Writer, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := appendMsgpack(&buf, tree); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func appendMsgpack(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			appendMsgpackInt(buf, n)
+		} else if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			appendUint(buf, u, 8)
+		} else {
+			f, err := v.Float64()
+			if err != nil {
+				return err
+			}
+			buf.WriteByte(0xcb)
+			appendUint(buf, math.Float64bits(f), 8)
+		}
+	case string:
+		n := len(v)
+		switch {
+		case n < 32:
+			buf.WriteByte(0xa0 | byte(n))
+		case n <= 0xff:
+			buf.WriteByte(0xd9)
+			appendUint(buf, uint64(n), 1)
+		case n <= 0xffff:
+			buf.WriteByte(0xda)
+			appendUint(buf, uint64(n), 2)
+		default:
+			buf.WriteByte(0xdb)
+			appendUint(buf, uint64(n), 4)
+		}
+		buf.WriteString(v)
+	case []any:
+		appendMsgpackLen(buf, len(v), 0x90, 0xdc)
+		for _, item := range v {
+			if err := appendMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		appendMsgpackLen(buf, len(v), 0x80, 0xde)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if err := appendMsgpack(buf, k); err != nil {
+				return err
+			}
+			if err := appendMsgpack(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+	return nil
+}
+
+func appendMsgpackLen(buf *bytes.Buffer, n int, fix, code16 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(code16)
+		appendUint(buf, uint64(n), 2)
+	default:
+		buf.WriteByte(code16 + 1)
+		appendUint(buf, uint64(n), 4)
+	}
+}
+
+func appendMsgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= 0x7f:
+		buf.WriteByte(byte(n))
+	case n >= -32 && n < 0:
+		buf.WriteByte(byte(n))
+	case n >= 0 && n <= 0xff:
+		buf.WriteByte(0xcc)
+		appendUint(buf, uint64(n), 1)
+	case n >= 0 && n <= 0xffff:
+		buf.WriteByte(0xcd)
+		appendUint(buf, uint64(n), 2)
+	case n >= 0 && n <= 0xffffffff:
+		buf.WriteByte(0xce)
+		appendUint(buf, uint64(n), 4)
+	case n >= 0:
+		buf.WriteByte(0xcf)
+		appendUint(buf, uint64(n), 8)
+	case n >= -0x80:
+		buf.WriteByte(0xd0)
+		appendUint(buf, uint64(n), 1)
+	case n >= -0x8000:
+		buf.WriteByte(0xd1)
+		appendUint(buf, uint64(n), 2)
+	case n >= -0x80000000:
+		buf.WriteByte(0xd2)
+		appendUint(buf, uint64(n), 4)
+	default:
+		buf.WriteByte(0xd3)
+		appendUint(buf, uint64(n), 8)
+	}
+}
+
+// appendUint 以大端序写入 size 字节
+func appendUint(buf *bytes.Buffer, v uint64, size int) {
+	for i := size - 1; i >= 0; i-- {
+		buf.WriteByte(byte(v >> (8 * i)))
+	}
+}
@@ -0,0 +1,151 @@
+package vigo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type renderItem struct {
+	ID      int       `json:"id" xml:"id"`
+	Name    string    `json:"name" xml:"name"`
+	Created time.Time `json:"created" xml:"created"`
+	Owner   *string   `json:"owner" xml:"owner"`
+	Secret  string    `json:"-" xml:"-"`
+	Tags    []string  `json:"tags" xml:"tags"`
+}
+
+func TestRender(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	items := []renderItem{{ID: 1, Name: "a,b", Created: created, Tags: []string{"x"}}}
+
+	tests := []struct {
+		name        string
+		accept      string
+		data        any
+		allowed     []string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"default json", "", map[string]int{"a": 1}, nil, "application/json; charset=utf-8", "{\"a\":1}\n", 0},
+		{"wildcard", "*/*", items, nil, "application/json; charset=utf-8", `"name":"a,b"`, 0},
+		{"csv", "text/csv", items, nil, "text/csv; charset=utf-8", "id,name,created,owner,tags\n1,\"a,b\",2025-01-02T03:04:05Z,,\"[\"\"x\"\"]\"\n", 0},
+		{"xml alias", "text/xml", renderItem{ID: 2}, nil, "application/xml; charset=utf-8", "<renderItem><id>2</id>", 0},
+		{"yaml", "application/yaml", map[string]int{"a": 1}, nil, "application/yaml; charset=utf-8", "a: 1\n", 0},
+		{"text", "text/plain", 42, nil, "text/plain; charset=utf-8", "42", 0},
+		{"q values", "application/json;q=0.5, application/yaml", map[string]int{"a": 1}, nil, "application/yaml; charset=utf-8", "a: 1", 0},
+		{"accept order", "text/csv, application/json", items, nil, "text/csv; charset=utf-8", "id,name", 0},
+		{"type wildcard", "text/*", items, nil, "text/csv; charset=utf-8", "id,name", 0},
+		{"csv needs struct list", "text/csv", map[string]int{"a": 1}, nil, "", "", 40600},
+		{"q zero", "application/json;q=0", 1, []string{"application/json"}, "", "", 40600},
+		{"allowed", "application/yaml, */*;q=0.1", map[string]int{"a": 1}, []string{"application/json"}, "application/json; charset=utf-8", `{"a":1}`, 0},
+		{"none", "image/png", items, nil, "", "", 40600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, _ := createTestX("GET", "/", nil)
+			defer release(x)
+			w := httptest.NewRecorder()
+			x.writer = w
+			if tt.accept != "" {
+				x.Request.Header.Set("Accept", tt.accept)
+			}
+			if tt.allowed != nil {
+				x.Set(RenderFormatsKey, tt.allowed)
+			}
+
+			err := x.Render(tt.data)
+			if w.Header().Get("Vary") != "Accept" {
+				t.Errorf("expected Vary: Accept, got %q", w.Header().Get("Vary"))
+			}
+			if tt.code != 0 {
+				var e *Error
+				if !errors.As(err, &e) || e.Code != tt.code {
+					t.Fatalf("expected code %d, got %v", tt.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type: expected %q, got %q", tt.contentType, got)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body: expected to contain %q, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRenderMsgpack(t *testing.T) {
+	var buf bytes.Buffer
+	data := map[string]any{"a": 1, "b": []any{true, nil, -1, 300, -200, 1.5, "hi"}}
+	if err := renderMsgpack(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	want := "82" + "a161" + "01" + "a162" + "97" + "c3" + "c0" + "ff" + "cd012c" + "d1ff38" + "cb3ff8000000000000" + "a26869"
+	if got := hex.EncodeToString(buf.Bytes()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestRegisterRendererConcurrent(t *testing.T) {
+	// 重复注册已有格式, 不改变全局状态; 以 -race 运行时检查协商与注册之间的数据竞争
+	var text *Renderer
+	for _, r := range getRenderers() {
+		if r.MediaType == "text/plain" {
+			text = r
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			RegisterRenderer(text)
+		}
+	}()
+	for range 100 {
+		if r := negotiateRenderer("text/plain", reflect.TypeOf(""), nil); r == nil || r.MediaType != "text/plain" {
+			t.Fatalf("unexpected renderer %v", r)
+		}
+	}
+	<-done
+}
+
+func TestRenderDocFormats(t *testing.T) {
+	r := NewRouter()
+	r.Get("/plain", "json only", func(x *X) ([]renderItem, error) { return nil, nil })
+	r.SubRouter("/all").SetVar(RenderFormatsKey, []string{}).
+		Get("/items", "list items", func(x *X) ([]renderItem, error) { return nil, nil })
+	r.SubRouter("/api").SetVar(RenderFormatsKey, []string{"application/json", "text/csv"}).
+		Get("/one", "get item", func(x *X) (*renderItem, error) { return nil, nil })
+	r.SubRouter("/env").SetVar(RenderFormatsKey, []string{}).SetVar(EnvelopeKey, &DefaultEnvelope{}).
+		Get("/items", "wrapped items", func(x *X) ([]renderItem, error) { return nil, nil })
+
+	formats := map[string][]string{}
+	for _, route := range r.Doc().Routes {
+		formats[route.Path] = route.Response.Formats
+	}
+	// 未设置 RenderFormatsKey 的路由不列出格式
+	if formats["/plain"] != nil {
+		t.Errorf("/plain: expected no formats, got %v", formats["/plain"])
+	}
+	// 按包装后的结构计算, 包装后不再是结构体切片, 不能输出 CSV
+	if want := []string{"application/json", "application/xml", "application/yaml", "application/msgpack"}; !reflect.DeepEqual(formats["/env/items"], want) {
+		t.Errorf("/env/items: expected %v, got %v", want, formats["/env/items"])
+	}
+	if want := []string{"application/json", "application/xml", "application/yaml", "application/msgpack", "text/csv"}; !reflect.DeepEqual(formats["/all/items"], want) {
+		t.Errorf("/all/items: expected %v, got %v", want, formats["/all/items"])
+	}
+	if want := []string{"application/json"}; !reflect.DeepEqual(formats["/api/one"], want) {
+		t.Errorf("/api/one: expected %v, got %v", want, formats["/api/one"])
+	}
+}
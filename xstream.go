@@ -0,0 +1,198 @@
+//
+// xstream.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/veypi/vigo/logv"
+)
+
+// StreamErrorTrailer 流式响应中途出错时写入的 trailer
+const StreamErrorTrailer = "X-Stream-Error"
+
+var boolType = reflect.TypeOf(true)
+
+// streamElemType 判断是否为 iter.Seq[T], iter.Seq2[T, error] 或 <-chan T, 返回元素类型
+func streamElemType(t reflect.Type) (reflect.Type, bool) {
+	if t == nil {
+		return nil, false
+	}
+	switch t.Kind() {
+	case reflect.Chan:
+		if t.ChanDir()&reflect.RecvDir != 0 {
+			return t.Elem(), true
+		}
+	case reflect.Func:
+		if t.NumIn() != 1 || t.NumOut() != 0 {
+			return nil, false
+		}
+		yield := t.In(0)
+		if yield.Kind() != reflect.Func || yield.NumOut() != 1 || yield.Out(0) != boolType {
+			return nil, false
+		}
+		switch yield.NumIn() {
+		case 1:
+			return yield.In(0), true
+		case 2:
+			if yield.In(1) == errorType {
+				return yield.In(0), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Stream 逐个元素写出 iter.Seq[T], iter.Seq2[T, error] 或 <-chan T, 每个元素写出后立即 flush
+//
+// Accept 包含 application/x-ndjson 时按行输出 NDJSON, 否则输出 JSON 数组.
+// 客户端断开后停止迭代; 中途出错时写入 X-Stream-Error trailer,
+// NDJSON 额外追加一行 {"error":{"code":..,"message":..}}, JSON 数组不闭合, 便于客户端识别截断.
+// 错误已写入响应, 因此只记录日志, 不再返回给错误处理函数
+func (x *X) Stream(data any) error {
+	rv := reflect.ValueOf(data)
+	if _, ok := streamElemType(rv.Type()); !ok {
+		return x.JSON(data)
+	}
+	ndjson := acceptsNDJSON(x.Request.Header.Get("Accept"))
+	if x.Header().Get("Content-Type") == "" {
+		if ndjson {
+			x.Header().Set("Content-Type", "application/x-ndjson")
+		} else {
+			x.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+	}
+	addVary(x.Header(), "Accept")
+	x.clearWriteDeadline()
+
+	s := &streamWriter{x: x, ndjson: ndjson}
+	if !ndjson {
+		s.write([]byte{'['})
+	}
+	switch rv.Kind() {
+	case reflect.Chan:
+		s.fromChan(rv)
+	case reflect.Func:
+		s.fromSeq(rv)
+	}
+	if s.err != nil {
+		s.fail(s.err)
+		return nil
+	}
+	if !ndjson && !s.closed {
+		s.write([]byte{']', '\n'})
+	}
+	return nil
+}
+
+func acceptsNDJSON(accept string) bool {
+	for _, item := range parseAccept(accept) {
+		mt := item.typ + "/" + item.sub
+		if item.q > 0 && (mt == "application/x-ndjson" || mt == "application/jsonl" || mt == "application/ndjson") {
+			return true
+		}
+	}
+	return false
+}
+
+type streamWriter struct {
+	x      *X
+	ndjson bool
+	count  int
+	err    error // 元素产生或编码的错误
+	closed bool  // 客户端断开或写入失败
+}
+
+func (s *streamWriter) write(b []byte) bool {
+	if s.closed {
+		return false
+	}
+	if _, err := s.x.Write(b); err != nil {
+		s.closed = true
+		return false
+	}
+	if f, ok := s.x.writer.(http.Flusher); ok {
+		f.Flush()
+	}
+	return true
+}
+
+// item 写出单个元素, 返回 false 时停止迭代
+func (s *streamWriter) item(v reflect.Value) bool {
+	if s.closed || s.x.Request.Context().Err() != nil {
+		s.closed = true
+		return false
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		s.err = err
+		return false
+	}
+	buf := make([]byte, 0, len(b)+1)
+	if !s.ndjson && s.count > 0 {
+		buf = append(buf, ',')
+	}
+	buf = append(buf, b...)
+	if s.ndjson {
+		buf = append(buf, '\n')
+	}
+	s.count++
+	return s.write(buf)
+}
+
+func (s *streamWriter) fromChan(ch reflect.Value) {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.x.Request.Context().Done())},
+	}
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		if chosen == 1 {
+			s.closed = true
+			return
+		}
+		if !ok || !s.item(v) {
+			return
+		}
+	}
+}
+
+func (s *streamWriter) fromSeq(seq reflect.Value) {
+	yieldType := seq.Type().In(0)
+	falseValue := []reflect.Value{reflect.ValueOf(false)}
+	trueValue := []reflect.Value{reflect.ValueOf(true)}
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		if len(args) == 2 && !args[1].IsNil() {
+			s.err = args[1].Interface().(error)
+			return falseValue
+		}
+		if !s.item(args[0]) {
+			return falseValue
+		}
+		return trueValue
+	})
+	seq.Call([]reflect.Value{yield})
+}
+
+func (s *streamWriter) fail(err error) {
+	code, msg := 50000, err.Error()
+	var e *Error
+	if errors.As(err, &e) {
+		code, msg = e.Code, e.Message
+	}
+	logv.Warn().Err(logv.Detailed(err)).Int("items", s.count).Msg("stream aborted")
+	s.x.Header().Set(http.TrailerPrefix+StreamErrorTrailer, strings.ReplaceAll(msg, "\n", " "))
+	if s.ndjson {
+		line, _ := json.Marshal(map[string]any{"error": map[string]any{"code": code, "message": msg}})
+		s.write(append(line, '\n'))
+	}
+}
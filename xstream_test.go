@@ -0,0 +1,123 @@
+package vigo
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	seq := func(n int) iter.Seq[int] {
+		return func(yield func(int) bool) {
+			for i := 1; i <= n; i++ {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	}
+	r := NewRouter()
+	r.After(func(x *X, data any) error { return x.JSON(data) })
+	r.Get("/seq", func(x *X) (iter.Seq[int], error) { return seq(3), nil })
+	r.Get("/seq2", func(x *X) (iter.Seq2[map[string]int, error], error) {
+		return func(yield func(map[string]int, error) bool) {
+			if !yield(map[string]int{"a": 1}, nil) {
+				return
+			}
+			yield(nil, ErrInvalidArg.WithString("broken"))
+		}, nil
+	})
+	r.Get("/chan", func(x *X) (<-chan string, error) {
+		ch := make(chan string, 2)
+		ch <- "a"
+		ch <- "b"
+		close(ch)
+		return ch, nil
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		contentType string
+		body        string
+		trailer     string
+	}{
+		{"seq array", "/seq", "", "application/json; charset=utf-8", "[1,2,3]\n", ""},
+		{"seq ndjson", "/seq", "application/x-ndjson", "application/x-ndjson", "1\n2\n3\n", ""},
+		{"chan array", "/chan", "application/json", "application/json; charset=utf-8", "[\"a\",\"b\"]\n", ""},
+		{"seq2 error ndjson", "/seq2", "application/x-ndjson", "application/x-ndjson", "{\"a\":1}\n{\"error\":{\"code\":40001,\"message\":\"invalid arg: broken\"}}\n", "invalid arg: broken"},
+		{"seq2 error array", "/seq2", "", "application/json; charset=utf-8", "[{\"a\":1}", "invalid arg: broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			res := w.Result()
+			if got := res.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type: expected %q, got %q", tt.contentType, got)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("body: expected %q, got %q", tt.body, w.Body.String())
+			}
+			if got := res.Trailer.Get(StreamErrorTrailer); got != tt.trailer {
+				t.Errorf("trailer: expected %q, got %q", tt.trailer, got)
+			}
+			if !w.Flushed {
+				t.Error("expected response to be flushed")
+			}
+		})
+	}
+}
+
+func TestStream_ClientDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	yielded := 0
+	seq := func(yield func(int) bool) {
+		for i := 0; i < 100; i++ {
+			yielded++
+			if i == 2 {
+				cancel()
+			}
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	x, _ := createTestX("GET", "/", nil)
+	defer release(x)
+	x.Request = x.Request.WithContext(ctx)
+	w := httptest.NewRecorder()
+	x.writer = w
+	if err := x.Stream(iter.Seq[int](seq)); err != nil {
+		t.Fatal(err)
+	}
+	if yielded != 3 || w.Body.String() != "[0,1" {
+		t.Errorf("expected stop after disconnect, yielded=%d body=%q", yielded, w.Body.String())
+	}
+
+	ch := make(chan int)
+	x2, _ := createTestX("GET", "/", nil)
+	defer release(x2)
+	x2.Request = x2.Request.WithContext(ctx)
+	x2.writer = httptest.NewRecorder()
+	if err := x2.Stream((<-chan int)(ch)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStream_DocResponse(t *testing.T) {
+	r := NewRouter()
+	r.Get("/items", "stream items", func(x *X) (iter.Seq[renderItem], error) { return nil, nil })
+	doc := r.Doc()
+	if len(doc.Routes) != 1 || doc.Routes[0].Response == nil || doc.Routes[0].Response.Type != "array" {
+		t.Fatalf("expected array response, got %+v", doc.Routes)
+	}
+}
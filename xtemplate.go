@@ -0,0 +1,245 @@
+//
+// xtemplate.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TemplateKey 路由变量名, 设置 x.Render(name, data) 使用的模板集
+const TemplateKey = "vigo.templates"
+
+// Templates 基于 fs.FS 的 html/template 模板集, 按页面缓存解析结果
+//
+//	tpl := vigo.NewTemplates(ufs.NewMultiFS(os.DirFS("views"), viewsFS)).
+//		Layouts("layouts/*.html").
+//		Base("base.html").
+//		Partials("partials/*.html").
+//		Funcs(template.FuncMap{"upper": strings.ToUpper})
+//	router.SetVar(vigo.TemplateKey, tpl)
+//	x.Render("pages/index.html", data)
+//
+// 每个页面与所有 layout、partial 组成一个模板集, 模板名为文件在 fsys 中的路径.
+// 配置了 layout 时执行 Base 指定的模板, 只有一个 layout 文件时可以不指定, 页面通过 {{define}} 覆盖 layout 中的 {{block}};
+// 未配置时直接执行页面. 设置了 vdev 环境变量时, 文件修改后会重新解析
+type Templates struct {
+	fsys     fs.FS
+	layouts  []string
+	base     string
+	partials []string
+	funcs    template.FuncMap
+	dev      bool
+
+	mu    sync.RWMutex
+	cache map[string]*templateEntry
+}
+
+type templateEntry struct {
+	tpl   *template.Template
+	entry string
+	files []string
+	mods  []time.Time
+}
+
+// NewTemplates 创建模板集, fsys 可以是 os.DirFS, embed.FS 或 ufs.NewMultiFS
+func NewTemplates(fsys fs.FS) *Templates {
+	return &Templates{
+		fsys:  fsys,
+		funcs: template.FuncMap{},
+		dev:   os.Getenv("vdev") != "",
+		cache: make(map[string]*templateEntry),
+	}
+}
+
+// Layouts 设置 layout 文件的 glob 模式
+func (t *Templates) Layouts(patterns ...string) *Templates {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.layouts = append(t.layouts, patterns...)
+	t.reset()
+	return t
+}
+
+// Base 设置配置了 layout 时执行的模板, 可以是 layout 文件路径、文件名 (如 base.html) 或 {{define}} 的模板名
+func (t *Templates) Base(name string) *Templates {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.base = name
+	t.reset()
+	return t
+}
+
+// Partials 设置公共片段文件的 glob 模式, 可通过 {{template "partials/nav.html" .}} 引用
+func (t *Templates) Partials(patterns ...string) *Templates {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.partials = append(t.partials, patterns...)
+	t.reset()
+	return t
+}
+
+// Funcs 追加所有模板共享的函数
+func (t *Templates) Funcs(funcs template.FuncMap) *Templates {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, v := range funcs {
+		t.funcs[k] = v
+	}
+	t.reset()
+	return t
+}
+
+// Dev 设置是否检查文件变化, 默认取决于 vdev 环境变量
+func (t *Templates) Dev(dev bool) *Templates {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dev = dev
+	return t
+}
+
+// Apply 作为中间件为当前请求设置模板集
+func (t *Templates) Apply(x *X) {
+	x.Set(TemplateKey, t)
+}
+
+func (t *Templates) reset() {
+	t.cache = make(map[string]*templateEntry)
+}
+
+// Execute 渲染页面
+func (t *Templates) Execute(w io.Writer, name string, data any) error {
+	e, err := t.get(name)
+	if err != nil {
+		return err
+	}
+	return e.tpl.ExecuteTemplate(w, e.entry, data)
+}
+
+func (t *Templates) get(name string) (*templateEntry, error) {
+	t.mu.RLock()
+	e := t.cache[name]
+	fresh := e != nil && (!t.dev || !t.changed(e, name))
+	t.mu.RUnlock()
+	if fresh {
+		return e, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, err := t.parse(name)
+	if err != nil {
+		return nil, err
+	}
+	t.cache[name] = e
+	return e, nil
+}
+
+// files 返回模板集的文件列表: layouts, partials, 页面, 以及其中 layout 文件的数量
+func (t *Templates) files(name string) ([]string, int, error) {
+	var files []string
+	layouts := 0
+	for i, pattern := range append(append([]string{}, t.layouts...), t.partials...) {
+		matches, err := fs.Glob(t.fsys, pattern)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, m := range matches {
+			if m != name && !slices.Contains(files, m) {
+				files = append(files, m)
+				if i < len(t.layouts) {
+					layouts++
+				}
+			}
+		}
+	}
+	return append(files, name), layouts, nil
+}
+
+func (t *Templates) parse(name string) (*templateEntry, error) {
+	files, layouts, err := t.files(name)
+	if err != nil {
+		return nil, err
+	}
+	e := &templateEntry{entry: name, files: files, mods: make([]time.Time, len(files))}
+	if layouts > 0 {
+		if e.entry, err = t.layoutEntry(files[:layouts]); err != nil {
+			return nil, err
+		}
+	}
+	e.tpl = template.New("").Funcs(t.funcs)
+	// 页面最后解析, 覆盖 layout 中的同名 block
+	for i, f := range files {
+		b, err := fs.ReadFile(t.fsys, f)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", f, err)
+		}
+		if _, err := e.tpl.New(f).Parse(string(b)); err != nil {
+			return nil, err
+		}
+		if info, err := fs.Stat(t.fsys, f); err == nil {
+			e.mods[i] = info.ModTime()
+		}
+	}
+	return e, nil
+}
+
+// layoutEntry 返回执行的 layout 模板名, 不依赖文件顺序
+func (t *Templates) layoutEntry(layouts []string) (string, error) {
+	if t.base == "" {
+		if len(layouts) == 1 {
+			return layouts[0], nil
+		}
+		return "", fmt.Errorf("multiple layouts %v, set the entry template with Templates.Base", layouts)
+	}
+	for _, f := range layouts {
+		if path.Base(f) == t.base {
+			return f, nil
+		}
+	}
+	return t.base, nil
+}
+
+// changed 检查文件列表或修改时间是否变化
+func (t *Templates) changed(e *templateEntry, name string) bool {
+	files, _, err := t.files(name)
+	if err != nil || !slices.Equal(files, e.files) {
+		return true
+	}
+	for i, f := range files {
+		info, err := fs.Stat(t.fsys, f)
+		if err != nil || !info.ModTime().Equal(e.mods[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (x *X) renderTemplate(name string, data any) error {
+	t, _ := x.Get(TemplateKey).(*Templates)
+	if t == nil {
+		return fmt.Errorf("no templates configured for %s, set vigo.TemplateKey first", name)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, name, data); err != nil {
+		return err
+	}
+	if x.Header().Get("Content-Type") == "" {
+		x.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	_, err := x.Write(buf.Bytes())
+	return err
+}
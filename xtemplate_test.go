@@ -0,0 +1,110 @@
+package vigo
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":    {Data: []byte(`<title>{{block "title" .}}Site{{end}}</title><main>{{block "content" .}}empty{{end}}</main>{{template "partials/footer.html" .}}`)},
+		"partials/footer.html": {Data: []byte(`<footer>{{upper .Name}}</footer>`)},
+		"pages/index.html":     {Data: []byte(`{{define "content"}}hello {{.Name}}{{end}}`)},
+		"pages/about.html":     {Data: []byte(`{{define "title"}}About{{end}}{{define "content"}}<b>{{.Name}}</b>{{end}}`)},
+	}
+	tpl := NewTemplates(fsys).
+		Layouts("layouts/*.html").
+		Partials("partials/*.html").
+		Funcs(template.FuncMap{"upper": strings.ToUpper}).
+		Dev(false)
+
+	r := NewRouter()
+	r.SetVar(TemplateKey, tpl)
+	r.Get("/{page}", func(x *X) error {
+		return x.Render("pages/"+x.PathParams.Get("page")+".html", map[string]string{"Name": "<vigo>"})
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/index")
+	if want := `<title>Site</title><main>hello &lt;vigo&gt;</main><footer>&lt;VIGO&gt;</footer>`; w.Body.String() != want {
+		t.Errorf("index: expected %q, got %q", want, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if want := `<title>About</title><main><b>&lt;vigo&gt;</b></main>`; !strings.HasPrefix(get("/about").Body.String(), want) {
+		t.Errorf("about: expected prefix %q, got %q", want, get("/about").Body.String())
+	}
+
+	// 缓存: 非开发模式下修改文件不生效
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}changed{{end}}`), ModTime: time.Now()}
+	if !strings.Contains(get("/index").Body.String(), "hello") {
+		t.Error("expected cached template")
+	}
+	tpl.Dev(true)
+	if !strings.Contains(get("/index").Body.String(), "changed") {
+		t.Error("expected template to be re-parsed in dev mode")
+	}
+	// 新增 partial 同样触发重新解析
+	fsys["partials/extra.html"] = &fstest.MapFile{Data: []byte(`overridden`)}
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{template "partials/extra.html"}}{{end}}`), ModTime: time.Now().Add(time.Second)}
+	if !strings.Contains(get("/index").Body.String(), "overridden") {
+		t.Errorf("expected new partial, got %q", get("/index").Body.String())
+	}
+}
+
+func TestTemplates_NoLayoutAndErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html": {Data: []byte(`<p>{{.}}</p>`)},
+		"bad.html":  {Data: []byte(`{{.Name`)},
+	}
+	x, _ := createTestX("GET", "/", nil)
+	defer release(x)
+	w := httptest.NewRecorder()
+	x.writer = w
+
+	if err := x.Render("page.html", "a"); err == nil {
+		t.Error("expected error without templates")
+	}
+	NewTemplates(fsys).Apply(x)
+	if err := x.Render("page.html", "a"); err != nil || w.Body.String() != "<p>a</p>" {
+		t.Errorf("unexpected result %q, %v", w.Body.String(), err)
+	}
+	if err := x.Render("missing.html", nil); err == nil {
+		t.Error("expected missing template error")
+	}
+	if err := x.Render("bad.html", nil); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestTemplates_Base(t *testing.T) {
+	// layouts/_head.html 按文件顺序排在入口 layout 之前
+	fsys := fstest.MapFS{
+		"layouts/_head.html": {Data: []byte(`{{define "head"}}<head>{{block "title" .}}Site{{end}}</head>{{end}}`)},
+		"layouts/site.html":  {Data: []byte(`<html>{{template "head" .}}{{block "content" .}}{{end}}</html>`)},
+		"pages/index.html":   {Data: []byte(`{{define "content"}}hello{{end}}`)},
+	}
+	tpl := NewTemplates(fsys).Layouts("layouts/*.html").Dev(false)
+	var buf strings.Builder
+	if err := tpl.Execute(&buf, "pages/index.html", nil); err == nil || !strings.Contains(err.Error(), "Templates.Base") {
+		t.Errorf("expected error for multiple layouts without base, got %v %q", err, buf.String())
+	}
+	want := "<html><head>Site</head>hello</html>"
+	for _, base := range []string{"site.html", "layouts/site.html"} {
+		buf.Reset()
+		if err := tpl.Base(base).Execute(&buf, "pages/index.html", nil); err != nil || buf.String() != want {
+			t.Errorf("base %s: expected %q, got %q %v", base, want, buf.String(), err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+//
+// xtls.go
+// Copyright (C) 2026 veypi <i@veypi.com>
+//
+// Distributed under terms of the MIT license.
+//
+
+package vigo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/veypi/vigo/logv"
+)
+
+// certCheckInterval 检查证书文件是否更新的最小间隔
+var certCheckInterval = 5 * time.Second
+
+// certReloader 在握手时检查证书文件的修改时间, 变化后重新加载证书和客户端 CA
+type certReloader struct {
+	certFile, keyFile, caFile string
+	interval                  time.Duration
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile, caFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile, interval: certCheckInterval}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := make([]string, 0, 3)
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range r.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *certReloader) load(modTime time.Time) error {
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("load tls certificate: %w", err)
+		}
+		cert = &c
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		b, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("load client ca: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.New("load client ca: no certificates found in " + r.caFile)
+		}
+	}
+	r.mu.Lock()
+	r.cert, r.pool, r.modTime, r.checked = cert, pool, modTime, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// reload 距上次检查超过 interval 且文件有更新时重新加载, 失败时继续使用旧证书
+func (r *certReloader) reload() {
+	r.mu.RLock()
+	due := time.Since(r.checked) >= r.interval
+	last := r.modTime
+	r.mu.RUnlock()
+	if !due {
+		return
+	}
+	modTime, err := r.latestModTime()
+	if err == nil && modTime.Equal(last) {
+		r.mu.Lock()
+		r.checked = time.Now()
+		r.mu.Unlock()
+		return
+	}
+	if err == nil {
+		err = r.load(modTime)
+	}
+	if err != nil {
+		logv.WithNoCaller.Warn().Err(err).Msg("reload tls certificate failed")
+		r.mu.Lock()
+		r.checked = time.Now()
+		r.mu.Unlock()
+		return
+	}
+	logv.WithNoCaller.Info().Msg("tls certificate reloaded")
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.reload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// configForClient 每次握手使用最新的客户端 CA
+func (r *certReloader) configForClient(base *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.reload()
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		r.mu.RLock()
+		cfg.ClientCAs = r.pool
+		r.mu.RUnlock()
+		return cfg, nil
+	}
+}
+
+// tlsConfig 合并 TlsCfg 与证书文件配置, 未启用 TLS 时返回 nil
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	enabled := c.TLSCertFile != "" ||
+		(c.TlsCfg != nil && (len(c.TlsCfg.Certificates) > 0 || c.TlsCfg.GetCertificate != nil || c.TlsCfg.GetConfigForClient != nil))
+	if !enabled {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.TlsCfg != nil {
+		cfg = c.TlsCfg.Clone()
+	}
+	if len(cfg.NextProtos) == 0 {
+		cfg.NextProtos = []string{"h2", "http/1.1"}
+	} else if !slices.Contains(cfg.NextProtos, "http/1.1") {
+		cfg.NextProtos = append(cfg.NextProtos, "http/1.1")
+	}
+	if c.TLSCertFile == "" && c.TLSClientCAFile == "" {
+		return cfg, nil
+	}
+	if c.TLSCertFile != "" && c.TLSKeyFile == "" {
+		return nil, errors.New("tls key file is required")
+	}
+	r, err := newCertReloader(c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	if c.TLSCertFile != "" {
+		cfg.GetCertificate = r.GetCertificate
+	}
+	if c.TLSClientCAFile != "" {
+		cfg.ClientCAs = r.pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		if c.TLSClientCertOptional {
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+		cfg.GetConfigForClient = r.configForClient(cfg.Clone())
+	}
+	return cfg, nil
+}
+
+// ClientCert 返回 mTLS 校验通过的客户端证书, 未提供或未校验时返回 nil
+func (x *X) ClientCert() *x509.Certificate {
+	if x.Request.TLS == nil || len(x.Request.TLS.VerifiedChains) == 0 || len(x.Request.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return x.Request.TLS.VerifiedChains[0][0]
+}
+
+// ClientSubject 返回校验通过的客户端证书 Subject, 如 CN=order-service,O=mesh
+func (x *X) ClientSubject() string {
+	if cert := x.ClientCert(); cert != nil {
+		return cert.Subject.String()
+	}
+	return ""
+}
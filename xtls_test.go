@@ -0,0 +1,142 @@
+package vigo
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, serial int64, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"vigo"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tpl, key
+	if parent == nil {
+		tpl.IsCA, tpl.BasicConstraintsValid = true, true
+	} else {
+		tpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string, mtime time.Time) {
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600)
+	if keyFile != "" {
+		os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+		os.Chtimes(keyFile, mtime, mtime)
+	}
+	os.Chtimes(certFile, mtime, mtime)
+}
+
+func (c *testCert) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestTLSFilesAndClientCert(t *testing.T) {
+	defer func(d time.Duration) { certCheckInterval = d }(certCheckInterval)
+	certCheckInterval = 0
+
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	ca := newTestCert(t, "ca", 1, nil, 0)
+	ca.write(t, caFile, "", time.Now().Add(-time.Minute))
+	newTestCert(t, "server-v1", 2, ca, x509.ExtKeyUsageServerAuth).write(t, certFile, keyFile, time.Now().Add(-time.Minute))
+	client := newTestCert(t, "order-service", 3, ca, x509.ExtKeyUsageClientAuth)
+
+	app, err := NewServer(WithDocPath(""), WithTLSFiles(certFile, keyFile), WithClientCA(caFile, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Router().Get("/whoami", func(x *X) { x.WriteString(x.ClientSubject()) })
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.listener = tls.NewListener(raw, app.tlsConfig)
+	if u := listenerURL(raw, app.tlsConfig != nil); u != "https://"+raw.Addr().String() {
+		t.Errorf("unexpected url %s", u)
+	}
+	go app.RunContext(context.Background())
+	defer app.Shutdown(context.Background())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) (string, string, error) {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		resp, err := c.Get("https://" + raw.Addr().String() + "/whoami")
+		if err != nil {
+			return "", "", err
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b), resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+	}
+
+	subject, serverCN, err := get(client.tls())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "CN=order-service,O=vigo" || serverCN != "server-v1" {
+		t.Errorf("unexpected subject %q server %q", subject, serverCN)
+	}
+	if _, _, err := get(); err == nil {
+		t.Error("request without client certificate should fail")
+	}
+
+	// 更新证书文件后无需重启
+	newTestCert(t, "server-v2", 4, ca, x509.ExtKeyUsageServerAuth).write(t, certFile, keyFile, time.Now())
+	if _, serverCN, err = get(client.tls()); err != nil || serverCN != "server-v2" {
+		t.Errorf("certificate not reloaded: %q %v", serverCN, err)
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	if _, err := NewServer(WithTLSFiles("missing.crt", "missing.key")); err == nil {
+		t.Error("expected error for missing certificate")
+	}
+	if _, err := NewServer(WithTLSFiles("tls.crt", "")); err == nil {
+		t.Error("expected error for missing key file")
+	}
+	app, err := NewServer()
+	if err != nil || app.tlsConfig != nil {
+		t.Errorf("tls should be disabled by default: %v", err)
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"time"
 	"unsafe"
 )
 
@@ -54,6 +56,9 @@ func (x *X) JSON(data any) error {
 	case int, uint, int8, uint8, int16, uint16, int32, uint32, int64, uint64, float32, float64, bool:
 		_, err = x.writer.Write((fmt.Appendf([]byte{}, "%v", v)))
 	default:
+		if _, ok := streamElemType(reflect.TypeOf(data)); ok {
+			return x.Stream(data)
+		}
 		b, err := json.Marshal(data)
 		if err != nil {
 			return err
@@ -102,6 +107,8 @@ func (x *X) File(path string) error {
 		return err
 	}
 
+	// 大文件下载不受 Config.WriteTimeout 限制
+	x.clearWriteDeadline()
 	// http.ServeContent handles Content-Type, Content-Length, Range requests, and Last-Modified
 	http.ServeContent(x.writer, x.Request, filepath.Base(path), fileInfo.ModTime(), file)
 	return nil
@@ -113,5 +120,11 @@ func (x *X) Flush() {
 		http.Error(x.writer, "Streaming unsupported!", http.StatusInternalServerError)
 		return
 	}
+	x.clearWriteDeadline()
 	flusher.Flush()
 }
+
+// clearWriteDeadline 流式响应 (SSE, x.Stream) 和文件下载不受 Config.WriteTimeout 限制
+func (x *X) clearWriteDeadline() {
+	_ = http.NewResponseController(x.writer).SetWriteDeadline(time.Time{})
+}